@@ -0,0 +1,83 @@
+// Package audit records the actions taken through the admin API.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record is an action taken by an operator.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Actor is who took the action, such as token or cert:<common name>, anonymous when authentication failed
+	Actor string `json:"actor"`
+	// Action is the method and route, such as DELETE /admin/sessions/:sessionID
+	Action string `json:"action"`
+	// Target is what the action applies to, such as the session ID
+	Target   string `json:"target,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	RemoteIP string `json:"remote_ip"`
+	Status   int    `json:"status"`
+}
+
+// Logger writes audit records as JSON lines.
+type Logger struct {
+	lock   sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// New returns a logger that writes to w.
+func New(w io.Writer) *Logger {
+	return &Logger{w: w}
+}
+
+// Open returns a logger that appends to the file at path, or writes to stdout when path is empty.
+func Open(path string) (*Logger, error) {
+	if path == "" {
+		return New(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open audit log %s, err: %w", path, err)
+	}
+	return &Logger{w: f, closer: f}, nil
+}
+
+// Log writes the record. Records are not buffered, so they survive a crash of the relay.
+func (l *Logger) Log(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("fail to marshal audit record, err: %w", err)
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, err := l.w.Write(append(buf, '\n')); err != nil {
+		return fmt.Errorf("fail to write audit record, err: %w", err)
+	}
+	return nil
+}
+
+// Close syncs and closes the audit log file.
+func (l *Logger) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closer == nil {
+		return nil
+	}
+	if f, ok := l.closer.(*os.File); ok {
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("fail to sync audit log, err: %w", err)
+		}
+	}
+	err := l.closer.Close()
+	l.closer = nil
+	return err
+}
@@ -0,0 +1,231 @@
+// Package client is the Go client of the relay protocol, it builds the URLs, sets the message_id header,
+// escapes participant IDs, computes hashes and retries failed requests, so apps don't have to.
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when the relay has nothing stored for the request.
+var ErrNotFound = errors.New("not found")
+
+// DefaultPollInterval is how often the client polls the relay while waiting.
+const DefaultPollInterval = time.Millisecond * 500
+
+// messageIDHeader carries the message ID of a keysign, it keeps the messages of several keysigns in one session apart.
+const messageIDHeader = "message_id"
+
+// StatusError is returned when the relay answers with an unexpected status.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d", e.Method, e.Path, e.StatusCode)
+}
+
+// Is makes a 404 match ErrNotFound.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// RetryPolicy is how failed requests are retried.
+// Network errors, 408, 429 and 5xx are retried, other statuses are returned right away.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a request, including the first one
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, it doubles after every attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy retries a request up to 5 times, waiting from 200ms up to 5s between attempts.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond * 200,
+		MaxBackoff:     time.Second * 5,
+	}
+}
+
+// backoff returns the wait before the given retry, with jitter so devices don't retry in lockstep.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.InitialBackoff
+	for i := 0; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Config is the configuration of the client.
+type Config struct {
+	// ServerURL is the base URL of the relay, it can include a path prefix
+	ServerURL string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Retry is the retry policy, DefaultRetryPolicy when MaxAttempts is 0
+	Retry RetryPolicy
+	// PollInterval is how often the client polls while waiting, DefaultPollInterval when 0
+	PollInterval time.Duration
+	// Transport delivers the messages of an inbox, a PollingTransport when nil
+	Transport Transport
+}
+
+// Client talks to a relay server.
+type Client struct {
+	serverURL    string
+	httpClient   *http.Client
+	retry        RetryPolicy
+	pollInterval time.Duration
+	transport    Transport
+}
+
+// New returns a new client of the relay at cfg.ServerURL.
+func New(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.ServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse server url %s, err: %w", cfg.ServerURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("server url %s has to be http or https", cfg.ServerURL)
+	}
+	c := &Client{
+		serverURL:    strings.TrimRight(cfg.ServerURL, "/"),
+		httpClient:   cfg.HTTPClient,
+		retry:        cfg.Retry,
+		pollInterval: cfg.PollInterval,
+		transport:    cfg.Transport,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.retry.MaxAttempts <= 0 {
+		c.retry = DefaultRetryPolicy()
+	}
+	if c.pollInterval <= 0 {
+		c.pollInterval = DefaultPollInterval
+	}
+	if c.transport == nil {
+		c.transport = &PollingTransport{}
+	}
+	return c, nil
+}
+
+// request is a request to the relay.
+type request struct {
+	method      string
+	path        string
+	messageID   string
+	contentType string
+	body        []byte
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= http.StatusInternalServerError
+}
+
+// do sends the request, retrying it according to the retry policy, and returns the body of a 2xx response.
+func (c *Client) do(ctx context.Context, r request) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(c.retry.backoff(attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		body, err := c.send(ctx, r)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !retryable(statusErr.StatusCode) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("fail after %d attempts, err: %w", c.retry.MaxAttempts, lastErr)
+}
+
+func (c *Client) send(ctx context.Context, r request) ([]byte, error) {
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, c.serverURL+r.path, body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request, err: %w", err)
+	}
+	if r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if r.messageID != "" {
+		req.Header.Set(messageIDHeader, r.messageID)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", r.method, r.path, err)
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read response of %s %s, err: %w", r.method, r.path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{Method: r.method, Path: r.path, StatusCode: resp.StatusCode}
+	}
+	return buf, nil
+}
+
+// poll calls check every poll interval until it reports done or the context is cancelled.
+func (c *Client) poll(ctx context.Context, check func() (bool, error)) error {
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sessionPath returns the path of a session route, the session ID is escaped.
+func sessionPath(prefix, sessionID string) string {
+	return prefix + "/" + url.PathEscape(sessionID)
+}
+
+// participantSegment escapes a participant ID, the relay query-unescapes it, so spaces and + survive.
+func participantSegment(participantID string) string {
+	return url.QueryEscape(participantID)
+}
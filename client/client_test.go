@@ -0,0 +1,362 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/vultisig/vultisig-relay/client"
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/server"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+func newRelay(t *testing.T) string {
+	t.Helper()
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	ts := httptest.NewServer(server.NewServer(&cfg, store).Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		_ = store.Close()
+	})
+	return ts.URL
+}
+
+// newGRPCRelay is newRelay that also serves the gRPC API in memory, it returns a connection to it.
+func newGRPCRelay(t *testing.T) (string, *grpc.ClientConn) {
+	t.Helper()
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	s := server.NewServer(&cfg, store)
+	ts := httptest.NewServer(s.Handler())
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	s.RegisterGRPC(g)
+	go func() { _ = g.Serve(lis) }()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		g.Stop()
+		ts.Close()
+		_ = store.Close()
+	})
+	return ts.URL, conn
+}
+
+func newClient(t *testing.T, serverURL string) *client.Client {
+	t.Helper()
+	c, err := client.New(client.Config{
+		ServerURL:    serverURL,
+		PollInterval: time.Millisecond * 10,
+		Retry:        client.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond * 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// TestKeysign runs a keysign between two devices with the client.
+func TestKeysign(t *testing.T) {
+	serverURL := newRelay(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	const sessionID = "session"
+	const messageID = "msg"
+	parties := []string{"iPhone 15+", "MacBook Pro"}
+	payload := []byte(`{"keysign":"payload"}`)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(parties))
+	for i, id := range parties {
+		c := newClient(t, serverURL)
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			errs[i] = func() error {
+				leader := i == 0
+				if leader {
+					hash, err := c.UploadSessionPayload(ctx, payload, sessionID)
+					if err != nil {
+						return err
+					}
+					if hash != client.Hash(payload) {
+						return fmt.Errorf("unexpected payload hash %s", hash)
+					}
+					if err := c.UploadSetupMessage(ctx, sessionID, messageID, []byte("setup")); err != nil {
+						return err
+					}
+				}
+				if err := c.JoinSession(ctx, sessionID, id); err != nil {
+					return err
+				}
+				if leader {
+					if _, err := c.WaitForParticipants(ctx, sessionID, parties); err != nil {
+						return err
+					}
+					if err := c.StartSession(ctx, sessionID, parties); err != nil {
+						return err
+					}
+				}
+				started, err := c.WaitForStart(ctx, sessionID)
+				if err != nil {
+					return err
+				}
+				if len(started) != len(parties) {
+					return fmt.Errorf("unexpected participants %v", started)
+				}
+				if got, err := c.GetSessionPayload(ctx, sessionID, client.Hash(payload)); err != nil || string(got) != string(payload) {
+					return fmt.Errorf("unexpected payload %q, err: %w", got, err)
+				}
+				if setup, err := c.WaitForSetupMessage(ctx, sessionID, messageID); err != nil || string(setup) != "setup" {
+					return fmt.Errorf("unexpected setup message %q, err: %w", setup, err)
+				}
+				peer := parties[1-i]
+				for seq := uint64(1); seq <= 3; seq++ {
+					err := c.PostMessage(ctx, sessionID, messageID, model.Message{
+						From:       id,
+						To:         []string{peer},
+						Body:       []byte(fmt.Sprintf("%d from %s", seq, id)),
+						SequenceNo: seq,
+					})
+					if err != nil {
+						return err
+					}
+				}
+				var received []model.Message
+				inbox := client.Inbox{SessionID: sessionID, ParticipantID: id, MessageID: messageID}
+				err = c.Receive(ctx, inbox, func(m model.Message) error {
+					received = append(received, m)
+					if len(received) == 3 {
+						return client.ErrDone
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				for n, m := range received {
+					if m.From != peer || m.SequenceNo != uint64(n+1) || m.Hash != client.Hash(m.Body) {
+						return fmt.Errorf("unexpected message %+v", m)
+					}
+				}
+				if leader {
+					if err := c.MarkKeysignFinished(ctx, sessionID, messageID, []byte("signature")); err != nil {
+						return err
+					}
+				}
+				if sig, err := c.WaitForKeysignFinished(ctx, sessionID, messageID); err != nil || string(sig) != "signature" {
+					return fmt.Errorf("unexpected signature %q, err: %w", sig, err)
+				}
+				if err := c.MarkComplete(ctx, sessionID, id); err != nil {
+					return err
+				}
+				_, err = c.WaitForComplete(ctx, sessionID, parties)
+				return err
+			}()
+		}(i, id)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newClient(t, serverURL)
+	for _, id := range parties {
+		messages, err := c.Inbox(ctx, sessionID, id, messageID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 0 {
+			t.Fatalf("expected received messages to be deleted, got %+v", messages)
+		}
+	}
+	if err := c.EndSession(ctx, sessionID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetSetupMessage(ctx, sessionID, messageID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after the session ended, got %v", err)
+	}
+}
+
+// TestGRPCTransport receives over the gRPC API the messages posted over HTTP.
+func TestGRPCTransport(t *testing.T) {
+	serverURL, conn := newGRPCRelay(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	sender := newClient(t, serverURL)
+	receiver, err := client.New(client.Config{ServerURL: serverURL, Transport: &client.GRPCTransport{Conn: conn}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inbox := client.Inbox{SessionID: "session", ParticipantID: "MacBook Pro", MessageID: "msg"}
+	for i := 1; i <= 3; i++ {
+		body := fmt.Sprintf("round %d", i)
+		m := model.Message{From: "iPhone 15+", To: []string{inbox.ParticipantID}, Body: []byte(body), Hash: client.Hash([]byte(body)), SequenceNo: uint64(i)}
+		if err := sender.PostMessage(ctx, inbox.SessionID, inbox.MessageID, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var received []string
+	err = receiver.Receive(ctx, inbox, func(m model.Message) error {
+		received = append(received, string(m.Body))
+		if len(received) == 3 {
+			return client.ErrDone
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(received) != "[round 1 round 2 round 3]" {
+		t.Fatalf("unexpected messages %v", received)
+	}
+	messages, err := sender.Inbox(ctx, inbox.SessionID, inbox.ParticipantID, inbox.MessageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("expected received messages to be acknowledged, got %+v", messages)
+	}
+}
+
+func TestPayloadAlgorithms(t *testing.T) {
+	serverURL := newRelay(t)
+	c := newClient(t, serverURL)
+	ctx := context.Background()
+	payload := []byte(`{"keysign":"payload"}`)
+	for _, algorithm := range []string{storage.HashBLAKE3, storage.HashSHA512256, storage.HashSHA256} {
+		h := storage.PayloadID{Algorithm: algorithm}.NewHash()
+		h.Write(payload)
+		// the SHA-256 is also written with its prefix
+		id := algorithm + ":" + hex.EncodeToString(h.Sum(nil))
+		resp, err := http.Post(serverURL+"/payload/"+id, "text/plain", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: fail to store payload, status: %d", algorithm, resp.StatusCode)
+		}
+		if got, err := c.GetPayload(ctx, id); err != nil || string(got) != string(payload) {
+			t.Fatalf("%s: unexpected payload %q, err: %v", algorithm, got, err)
+		}
+	}
+
+	// a payload is verified with the algorithm of its ID
+	h := storage.PayloadID{Algorithm: storage.HashBLAKE3}.NewHash()
+	h.Write(payload)
+	id := storage.HashBLAKE3 + ":" + hex.EncodeToString(h.Sum(nil))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tampered"))
+	}))
+	defer ts.Close()
+	if _, err := newClient(t, ts.URL).GetPayload(ctx, id); err == nil {
+		t.Fatal("expected a payload that doesn't match its ID to be refused")
+	}
+	if _, err := c.GetPayload(ctx, "md5:"+hex.EncodeToString(make([]byte, 16))); err == nil {
+		t.Fatal("expected an ID of an unknown algorithm to be refused")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch r.URL.Path {
+		case "/flaky/ping":
+			// the relay is restarting, it fails twice
+			if n <= 2 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = io.WriteString(w, "Voltix Router is running")
+		case "/missing/ping":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		prefix    string
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "retried until success", prefix: "/flaky", wantCalls: 3},
+		{name: "not found is not retried", prefix: "/missing", wantErr: client.ErrNotFound, wantCalls: 1},
+		{name: "gives up after max attempts", prefix: "/down", wantErr: &client.StatusError{}, wantCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			// the path prefix of the server url is kept
+			err := newClient(t, ts.URL+tt.prefix).Ping(context.Background())
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			case *client.StatusError:
+				if !errors.As(err, &want) || want.StatusCode != http.StatusBadGateway {
+					t.Fatalf("expected a 502 status error, got %v", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("expected %v, got %v", want, err)
+				}
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Fatalf("expected %d calls, got %d", tt.wantCalls, calls.Load())
+			}
+		})
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	c, err := client.New(client.Config{
+		ServerURL: ts.URL,
+		Retry:     client.RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if err := c.Ping(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the backoff to stop when the context is done, got %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// Hash returns the hex encoded sha256 of data, it is the hash of payloads and the default hash of messages.
+func Hash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func (c *Client) postJSON(ctx context.Context, path string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("fail to marshal request of %s, err: %w", path, err)
+	}
+	_, err = c.do(ctx, request{method: http.MethodPost, path: path, contentType: "application/json", body: buf})
+	return err
+}
+
+func (c *Client) getParticipants(ctx context.Context, path string) ([]string, error) {
+	buf, err := c.do(ctx, request{method: http.MethodGet, path: path})
+	if err != nil {
+		return nil, err
+	}
+	var participants []string
+	if err := json.Unmarshal(buf, &participants); err != nil {
+		return nil, fmt.Errorf("fail to decode participants of %s, err: %w", path, err)
+	}
+	return participants, nil
+}
+
+func (c *Client) waitForParticipants(ctx context.Context, path string, expected []string) ([]string, error) {
+	var participants []string
+	err := c.poll(ctx, func() (bool, error) {
+		var err error
+		participants, err = c.getParticipants(ctx, path)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return false, err
+		}
+		if len(expected) == 0 {
+			return len(participants) > 0, nil
+		}
+		return containsAll(participants, expected), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+func containsAll(list, expected []string) bool {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+	for _, item := range expected {
+		if !set[item] {
+			return false
+		}
+	}
+	return true
+}
+
+// Ping checks the relay is reachable.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, request{method: http.MethodGet, path: "/ping"})
+	return err
+}
+
+// JoinSession adds the participant to the session, the session is created by the first participant.
+func (c *Client) JoinSession(ctx context.Context, sessionID, participantID string) error {
+	return c.postJSON(ctx, sessionPath("", sessionID), []string{participantID})
+}
+
+// GetParticipants returns the participants that joined the session.
+func (c *Client) GetParticipants(ctx context.Context, sessionID string) ([]string, error) {
+	return c.getParticipants(ctx, sessionPath("", sessionID))
+}
+
+// WaitForParticipants waits until every expected participant joined the session, it returns all the participants.
+func (c *Client) WaitForParticipants(ctx context.Context, sessionID string, expected []string) ([]string, error) {
+	return c.waitForParticipants(ctx, sessionPath("", sessionID), expected)
+}
+
+// EndSession deletes the session with all its messages, markers and setup messages.
+func (c *Client) EndSession(ctx context.Context, sessionID string) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: sessionPath("", sessionID)})
+	return err
+}
+
+// StartSession starts the ceremony with the given participants.
+func (c *Client) StartSession(ctx context.Context, sessionID string, participants []string) error {
+	return c.postJSON(ctx, sessionPath("/start", sessionID), participants)
+}
+
+// WaitForStart waits until the ceremony is started, it returns the participants of the ceremony.
+func (c *Client) WaitForStart(ctx context.Context, sessionID string) ([]string, error) {
+	return c.waitForParticipants(ctx, sessionPath("/start", sessionID), nil)
+}
+
+// MarkComplete marks the ceremony as complete for the participant.
+func (c *Client) MarkComplete(ctx context.Context, sessionID, participantID string) error {
+	return c.postJSON(ctx, sessionPath("/complete", sessionID), []string{participantID})
+}
+
+// GetCompleted returns the participants that completed the ceremony.
+func (c *Client) GetCompleted(ctx context.Context, sessionID string) ([]string, error) {
+	return c.getParticipants(ctx, sessionPath("/complete", sessionID))
+}
+
+// WaitForComplete waits until every expected participant completed the ceremony.
+func (c *Client) WaitForComplete(ctx context.Context, sessionID string, expected []string) ([]string, error) {
+	return c.waitForParticipants(ctx, sessionPath("/complete", sessionID), expected)
+}
+
+// PostMessage sends the message to every participant in message.To.
+// The session ID is filled in and the hash is computed from the body when they are empty.
+func (c *Client) PostMessage(ctx context.Context, sessionID, messageID string, message model.Message) error {
+	if message.SessionID == "" {
+		message.SessionID = sessionID
+	}
+	if message.Hash == "" {
+		message.Hash = Hash(message.Body)
+	}
+	buf, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("fail to marshal message, err: %w", err)
+	}
+	_, err = c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        sessionPath("/message", sessionID),
+		messageID:   messageID,
+		contentType: "application/json",
+		body:        buf,
+	})
+	return err
+}
+
+// Inbox returns the messages waiting for the participant.
+func (c *Client) Inbox(ctx context.Context, sessionID, participantID, messageID string) ([]model.Message, error) {
+	buf, err := c.do(ctx, request{
+		method:    http.MethodGet,
+		path:      sessionPath("/message", sessionID) + "/" + participantSegment(participantID),
+		messageID: messageID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	messages := []model.Message{}
+	if len(buf) == 0 {
+		return messages, nil
+	}
+	if err := json.Unmarshal(buf, &messages); err != nil {
+		return nil, fmt.Errorf("fail to decode messages, err: %w", err)
+	}
+	return messages, nil
+}
+
+// DeleteMessage removes a processed message from the inbox of the participant.
+func (c *Client) DeleteMessage(ctx context.Context, sessionID, participantID, messageID, hash string) error {
+	_, err := c.do(ctx, request{
+		method:    http.MethodDelete,
+		path:      sessionPath("/message", sessionID) + "/" + participantSegment(participantID) + "/" + hash,
+		messageID: messageID,
+	})
+	return err
+}
+
+// Receive delivers the messages of the inbox to handle with the transport of the client,
+// until the context is cancelled or handle returns an error, see Transport.
+func (c *Client) Receive(ctx context.Context, inbox Inbox, handle func(model.Message) error) error {
+	return c.transport.Receive(ctx, c, inbox, handle)
+}
+
+// UploadPayload stores the payload of a keysign, it returns the hash to share with the other participants.
+func (c *Client) UploadPayload(ctx context.Context, payload []byte) (string, error) {
+	return c.UploadSessionPayload(ctx, payload)
+}
+
+// UploadSessionPayload stores the payload of a keysign for the sessions, only they can read it and it is deleted
+// once they are all ended. It returns the hash to share with the other participants.
+func (c *Client) UploadSessionPayload(ctx context.Context, payload []byte, sessionIDs ...string) (string, error) {
+	hash := Hash(payload)
+	query := url.Values{"session_id": sessionIDs}
+	path := "/payload/" + hash
+	if len(sessionIDs) > 0 {
+		path += "?" + query.Encode()
+	}
+	_, err := c.do(ctx, request{method: http.MethodPost, path: path, body: payload})
+	if err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// GetPayload returns the payload with the given ID, it is verified against the ID.
+func (c *Client) GetPayload(ctx context.Context, hash string) ([]byte, error) {
+	return c.GetSessionPayload(ctx, "", hash)
+}
+
+// GetSessionPayload returns the payload with the given ID stored for the session, it is verified with the algorithm
+// the ID names: a bare hex digest is a SHA-256, other algorithms are written as in blake3:9f86d0...
+func (c *Client) GetSessionPayload(ctx context.Context, sessionID, hash string) ([]byte, error) {
+	payloadID, err := storage.ParsePayloadID(hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload ID %s, err: %w", hash, err)
+	}
+	path := "/payload/" + url.PathEscape(hash)
+	if sessionID != "" {
+		path += "?" + url.Values{"session_id": {sessionID}}.Encode()
+	}
+	buf, err := c.do(ctx, request{method: http.MethodGet, path: path})
+	if err != nil {
+		return nil, err
+	}
+	h := payloadID.NewHash()
+	h.Write(buf)
+	if hex.EncodeToString(h.Sum(nil)) != payloadID.Digest {
+		return nil, fmt.Errorf("payload does not match hash %s", hash)
+	}
+	return buf, nil
+}
+
+// UploadSetupMessage stores the setup message of the ceremony.
+func (c *Client) UploadSetupMessage(ctx context.Context, sessionID, messageID string, setup []byte) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: sessionPath("/setup-message", sessionID), messageID: messageID, body: setup})
+	return err
+}
+
+// GetSetupMessage returns the setup message of the ceremony, or ErrNotFound when it wasn't uploaded yet.
+func (c *Client) GetSetupMessage(ctx context.Context, sessionID, messageID string) ([]byte, error) {
+	return c.do(ctx, request{method: http.MethodGet, path: sessionPath("/setup-message", sessionID), messageID: messageID})
+}
+
+// WaitForSetupMessage waits until the setup message of the ceremony is uploaded.
+func (c *Client) WaitForSetupMessage(ctx context.Context, sessionID, messageID string) ([]byte, error) {
+	return c.waitForValue(ctx, func() ([]byte, error) { return c.GetSetupMessage(ctx, sessionID, messageID) })
+}
+
+// MarkKeysignFinished stores the result of the keysign of the message, usually the signature.
+func (c *Client) MarkKeysignFinished(ctx context.Context, sessionID, messageID string, result []byte) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: sessionPath("/complete", sessionID) + "/keysign", messageID: messageID, body: result})
+	return err
+}
+
+// GetKeysignFinished returns the result of the keysign of the message, or ErrNotFound when it isn't finished.
+func (c *Client) GetKeysignFinished(ctx context.Context, sessionID, messageID string) ([]byte, error) {
+	return c.do(ctx, request{method: http.MethodGet, path: sessionPath("/complete", sessionID) + "/keysign", messageID: messageID})
+}
+
+// WaitForKeysignFinished waits until the keysign of the message is finished and returns its result.
+func (c *Client) WaitForKeysignFinished(ctx context.Context, sessionID, messageID string) ([]byte, error) {
+	return c.waitForValue(ctx, func() ([]byte, error) { return c.GetKeysignFinished(ctx, sessionID, messageID) })
+}
+
+func (c *Client) waitForValue(ctx context.Context, get func() ([]byte, error)) ([]byte, error) {
+	var value []byte
+	err := c.poll(ctx, func() (bool, error) {
+		var err error
+		value, err = get()
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
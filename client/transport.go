@@ -0,0 +1,144 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/relaypb"
+)
+
+// ErrDone is returned by a message handler to stop receiving once the message is accepted.
+var ErrDone = errors.New("done receiving")
+
+// Inbox identifies the messages addressed to a participant of a session.
+type Inbox struct {
+	SessionID     string
+	ParticipantID string
+	// MessageID is the message of a keysign, empty for keygen
+	MessageID string
+}
+
+// Transport delivers the messages of an inbox, by polling the HTTP API or from the Exchange stream of the gRPC API.
+type Transport interface {
+	// Receive calls handle for every message of the inbox, once per message and in arrival order,
+	// until the context is cancelled or handle returns an error, which Receive returns.
+	// A message is removed from the inbox after handle accepted it, returning ErrDone accepts the message
+	// and stops Receive without an error.
+	Receive(ctx context.Context, c *Client, inbox Inbox, handle func(model.Message) error) error
+}
+
+var _ Transport = (*PollingTransport)(nil)
+
+// PollingTransport polls the inbox.
+type PollingTransport struct {
+	// Interval is how often the inbox is polled, the poll interval of the client when 0
+	Interval time.Duration
+}
+
+func (t *PollingTransport) Receive(ctx context.Context, c *Client, inbox Inbox, handle func(model.Message) error) error {
+	interval := t.Interval
+	if interval <= 0 {
+		interval = c.pollInterval
+	}
+	// seen keeps a message from being handled twice when it couldn't be deleted
+	seen := make(map[string]bool)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		messages, err := c.Inbox(ctx, inbox.SessionID, inbox.ParticipantID, inbox.MessageID)
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			var handleErr error
+			if !seen[m.Hash] {
+				handleErr = handle(m)
+				if handleErr != nil && !errors.Is(handleErr, ErrDone) {
+					return handleErr
+				}
+				seen[m.Hash] = true
+			}
+			if err := c.DeleteMessage(ctx, inbox.SessionID, inbox.ParticipantID, inbox.MessageID, m.Hash); err != nil {
+				return err
+			}
+			if handleErr != nil {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+var _ Transport = (*GRPCTransport)(nil)
+
+// GRPCTransport receives the messages from the Exchange stream of the gRPC API, the relay pushes them to the client.
+type GRPCTransport struct {
+	// Conn is the connection to the gRPC port of the relay
+	Conn grpc.ClientConnInterface
+}
+
+func (t *GRPCTransport) Receive(ctx context.Context, c *Client, inbox Inbox, handle func(model.Message) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := relaypb.NewRelayServiceClient(t.Conn).Exchange(ctx)
+	if err != nil {
+		return fmt.Errorf("fail to open exchange stream, err: %w", err)
+	}
+	open := &relaypb.Inbox{SessionId: inbox.SessionID, ParticipantId: inbox.ParticipantID, MessageId: inbox.MessageID}
+	if err := stream.Send(&relaypb.ExchangeRequest{Request: &relaypb.ExchangeRequest_Open{Open: open}}); err != nil {
+		return fmt.Errorf("fail to open inbox, err: %w", err)
+	}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("fail to receive message, err: %w", err)
+		}
+		m := resp.GetMessage()
+		handleErr := handle(model.Message{
+			SessionID:  m.GetSessionId(),
+			From:       m.GetFrom(),
+			To:         m.GetTo(),
+			Body:       m.GetBody(),
+			Hash:       m.GetHash(),
+			SequenceNo: m.GetSequenceNo(),
+		})
+		if handleErr != nil && !errors.Is(handleErr, ErrDone) {
+			return handleErr
+		}
+		if err := stream.Send(&relaypb.ExchangeRequest{Request: &relaypb.ExchangeRequest_Ack{Ack: &relaypb.Ack{Hash: m.GetHash()}}}); err != nil {
+			return fmt.Errorf("fail to acknowledge message, err: %w", err)
+		}
+		if handleErr != nil {
+			return closeExchange(stream)
+		}
+	}
+}
+
+// closeExchange half closes the stream and waits for the relay to end it, so the acknowledgements are not lost.
+// Messages received meanwhile are not acknowledged, they stay in the inbox.
+func closeExchange(stream relaypb.RelayService_ExchangeClient) error {
+	if err := stream.CloseSend(); err != nil {
+		return fmt.Errorf("fail to close exchange stream, err: %w", err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("fail to close exchange stream, err: %w", err)
+		}
+	}
+}
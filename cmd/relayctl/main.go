@@ -0,0 +1,415 @@
+// Command relayctl lets operators inspect the sessions of a relay, it reads the storage of the relay directly,
+// using the same configuration file, RELAY_* environment variables and flags as the relay.
+// With -admin-url it goes through the admin API of a running relay instead, authenticated with admin.token.
+//
+//	relayctl [flags] sessions           list active sessions
+//	relayctl [flags] session <id>       show participants, state, TTL and message counts per inbox
+//	relayctl [flags] expire <id>        delete a session and everything it tracks
+//	relayctl [flags] payload <hash>     write a payload to stdout
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/server"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// exit codes of relayctl
+const (
+	exitOK      = 0
+	exitFailed  = 1
+	exitUsage   = 2
+	exitMissing = 3
+)
+
+const usage = `usage: relayctl [flags] <command> [args]
+
+commands:
+  sessions          list active sessions
+  session <id>      show participants, state, TTL and message counts per inbox
+  expire <id>       delete a session and everything it tracks
+  payload <hash>    write a payload to stdout
+
+flags:
+  -admin-url <url>  use the admin API of the relay at url instead of its storage
+
+the other flags are the relay flags, run relayctl -h to list them`
+
+// env is what relayctl uses of the process, tests replace it.
+type env struct {
+	lookupEnv func(string) (string, bool)
+	// openStorage returns the storage of the relay, relayctl closes it when the command is done
+	openStorage func(cfg *config.Config) (storage.Storage, error)
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	code := run(ctx, os.Args[1:], env{
+		lookupEnv:   os.LookupEnv,
+		openStorage: storage.New,
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+	})
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command in args and returns the exit code of relayctl.
+func run(ctx context.Context, args []string, e env) int {
+	stderr := e.stderr
+	adminURL, args, err := extractAdminURL(args)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	cfg, opts, err := config.Load(args, e.lookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(stderr, usage)
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if len(opts.Args) == 0 {
+		fmt.Fprintln(stderr, usage)
+		return exitUsage
+	}
+	var b backend
+	if adminURL != "" {
+		b, err = newAdminBackend(adminURL, cfg.Admin.Token)
+	} else {
+		b, err = newStorageBackend(cfg, e.openStorage)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailed
+	}
+	defer func() {
+		if err := b.Close(); err != nil {
+			fmt.Fprintln(stderr, "fail to close", err)
+		}
+	}()
+	c := &ctl{backend: b, out: e.stdout}
+
+	command, params := opts.Args[0], opts.Args[1:]
+	switch {
+	case command == "sessions" && len(params) == 0:
+		err = c.sessions(ctx)
+	case command == "session" && len(params) == 1:
+		err = c.session(ctx, params[0])
+	case command == "expire" && len(params) == 1:
+		err = c.expire(ctx, params[0])
+	case command == "payload" && len(params) == 1:
+		err = c.payload(ctx, params[0])
+	default:
+		fmt.Fprintln(stderr, usage)
+		return exitUsage
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		fmt.Fprintln(stderr, err)
+		return exitMissing
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// extractAdminURL removes the -admin-url flag from args, the other flags are parsed by config.Load.
+func extractAdminURL(args []string) (string, []string, error) {
+	var adminURL string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			// flags end at the command
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "admin-url" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return "", nil, errors.New("flag needs an argument: -admin-url")
+			}
+			i++
+			value = args[i]
+		}
+		adminURL = value
+	}
+	return adminURL, rest, nil
+}
+
+// backend is where relayctl reads sessions from.
+type backend interface {
+	// Sessions returns the active sessions ordered by ID.
+	Sessions(ctx context.Context) ([]storage.SessionInfo, error)
+	Session(ctx context.Context, sessionID string) (storage.SessionInfo, error)
+	// Expire deletes the session, or returns storage.ErrNotFound.
+	Expire(ctx context.Context, sessionID string) error
+	Payload(ctx context.Context, hash string) ([]byte, error)
+	Close() error
+}
+
+// storageBackend reads the storage of the relay directly.
+type storageBackend struct {
+	store     storage.Storage
+	inspector storage.Inspector
+}
+
+func newStorageBackend(cfg *config.Config, openStorage func(cfg *config.Config) (storage.Storage, error)) (*storageBackend, error) {
+	store, err := openStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	inspector, ok := storage.AsInspector(store)
+	if !ok {
+		_ = store.Close()
+		return nil, fmt.Errorf("%s storage can't be inspected", store.Type())
+	}
+	return &storageBackend{store: store, inspector: inspector}, nil
+}
+
+func (b *storageBackend) Sessions(ctx context.Context) ([]storage.SessionInfo, error) {
+	sessionIDs, err := storage.ListSessions(ctx, b.inspector)
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]storage.SessionInfo, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		info, err := storage.InspectSession(ctx, b.store, b.inspector, sessionID)
+		if errors.Is(err, storage.ErrNotFound) {
+			// expired between listing and inspecting
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, info)
+	}
+	return sessions, nil
+}
+
+func (b *storageBackend) Session(ctx context.Context, sessionID string) (storage.SessionInfo, error) {
+	return storage.InspectSession(ctx, b.store, b.inspector, sessionID)
+}
+
+func (b *storageBackend) Expire(ctx context.Context, sessionID string) error {
+	if _, err := storage.InspectSession(ctx, b.store, b.inspector, sessionID); err != nil {
+		return err
+	}
+	if err := storage.ReleaseSessionPayloads(ctx, b.store, sessionID); err != nil {
+		return err
+	}
+	return b.store.DeleteSession(ctx, sessionID)
+}
+
+func (b *storageBackend) Payload(ctx context.Context, hash string) ([]byte, error) {
+	payloadID, err := storage.ParsePayloadID(hash)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := storage.GetPayload(ctx, b.store, payloadID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := payload.NewReader(ctx, 0, payload.Size())
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func (b *storageBackend) Close() error {
+	return b.store.Close()
+}
+
+// adminBackend goes through the admin API of a running relay.
+type adminBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func newAdminBackend(adminURL, token string) (*adminBackend, error) {
+	if _, err := url.ParseRequestURI(adminURL); err != nil {
+		return nil, fmt.Errorf("invalid admin url %s, err: %w", adminURL, err)
+	}
+	if token == "" {
+		return nil, errors.New("admin.token is required with -admin-url")
+	}
+	return &adminBackend{
+		baseURL: strings.TrimRight(adminURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: time.Second * 30},
+	}, nil
+}
+
+func (b *adminBackend) do(ctx context.Context, method, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create request, err: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fail to %s %s, err: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read response of %s %s, err: %w", method, path, err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("fail to %s %s, err: %w", method, path, storage.ErrNotFound)
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		return nil, fmt.Errorf("fail to %s %s, status: %s", method, path, resp.Status)
+	}
+	return buf, nil
+}
+
+func (b *adminBackend) Sessions(ctx context.Context) ([]storage.SessionInfo, error) {
+	var sessions []storage.SessionInfo
+	cursor := ""
+	for {
+		buf, err := b.do(ctx, http.MethodGet, "/admin/sessions?cursor="+url.QueryEscape(cursor))
+		if err != nil {
+			return nil, err
+		}
+		var page server.SessionPage
+		if err := json.Unmarshal(buf, &page); err != nil {
+			return nil, fmt.Errorf("fail to decode sessions, err: %w", err)
+		}
+		sessions = append(sessions, page.Sessions...)
+		if page.NextCursor == "" {
+			return sessions, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (b *adminBackend) Session(ctx context.Context, sessionID string) (storage.SessionInfo, error) {
+	var info storage.SessionInfo
+	buf, err := b.do(ctx, http.MethodGet, "/admin/sessions/"+url.PathEscape(sessionID))
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(buf, &info); err != nil {
+		return info, fmt.Errorf("fail to decode session, err: %w", err)
+	}
+	return info, nil
+}
+
+func (b *adminBackend) Expire(ctx context.Context, sessionID string) error {
+	_, err := b.do(ctx, http.MethodDelete, "/admin/sessions/"+url.PathEscape(sessionID))
+	return err
+}
+
+func (b *adminBackend) Payload(ctx context.Context, hash string) ([]byte, error) {
+	// payloads are served by the relay API, which reports invalid IDs as not found
+	if _, err := storage.ParsePayloadID(hash); err != nil {
+		return nil, err
+	}
+	return b.do(ctx, http.MethodGet, "/payload/"+url.PathEscape(hash))
+}
+
+func (b *adminBackend) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
+
+// ctl runs the commands against a backend.
+type ctl struct {
+	backend backend
+	out     io.Writer
+}
+
+func (c *ctl) sessions(ctx context.Context) error {
+	sessions, err := c.backend.Sessions(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tSTATE\tPARTICIPANTS\tMESSAGES\tTTL")
+	for _, info := range sessions {
+		messages := 0
+		for _, inbox := range info.Inboxes {
+			messages += inbox.Messages
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%ds\n", info.SessionID, info.State, len(info.Participants), messages, info.TTLSeconds)
+	}
+	return w.Flush()
+}
+
+func (c *ctl) session(ctx context.Context, sessionID string) error {
+	info, err := c.backend.Session(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "session:\t%s\n", info.SessionID)
+	fmt.Fprintf(w, "state:\t%s\n", info.State)
+	fmt.Fprintf(w, "ttl:\t%ds\n", info.TTLSeconds)
+	fmt.Fprintf(w, "participants:\t%s\n", strings.Join(info.Participants, ", "))
+	fmt.Fprintf(w, "started:\t%s\n", strings.Join(info.Started, ", "))
+	fmt.Fprintf(w, "completed:\t%s\n", strings.Join(info.Completed, ", "))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out)
+	w = tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INBOX\tMESSAGE ID\tMESSAGES")
+	for _, inbox := range info.Inboxes {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", inbox.ParticipantID, inbox.MessageID, inbox.Messages)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out)
+	w = tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tKIND\tTTL")
+	for _, key := range info.Keys {
+		fmt.Fprintf(w, "%s\t%s\t%ds\n", key.Key, key.Kind, key.TTLSeconds)
+	}
+	return w.Flush()
+}
+
+func (c *ctl) expire(ctx context.Context, sessionID string) error {
+	if err := c.backend.Expire(ctx, sessionID); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "session %s expired\n", sessionID)
+	return nil
+}
+
+func (c *ctl) payload(ctx context.Context, hash string) error {
+	value, err := c.backend.Payload(ctx, hash)
+	if err != nil {
+		return err
+	}
+	_, err = c.out.Write(value)
+	return err
+}
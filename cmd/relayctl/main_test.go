@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vultisig/vultisig-relay/audit"
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/server"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+const adminToken = "relayctl-test-token"
+
+// sharedStorage keeps the storage of the test open when relayctl closes it.
+type sharedStorage struct {
+	storage.Storage
+}
+
+func (s sharedStorage) Close() error {
+	return nil
+}
+
+func (s sharedStorage) Unwrap() storage.Storage {
+	return s.Storage
+}
+
+// testRelay is a relay with in-memory storage, relayctl reaches it through its storage or its admin API.
+type testRelay struct {
+	store storage.Storage
+	url   string
+}
+
+func newTestRelay(t *testing.T) *testRelay {
+	t.Helper()
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatalf("fail to create storage, err: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Admin.Token = adminToken
+	s := server.NewServer(&cfg, store)
+	s.SetAuditLogger(audit.New(io.Discard))
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		_ = store.Close()
+	})
+	return &testRelay{store: store, url: ts.URL}
+}
+
+// post sends a request to the relay API, to create what relayctl inspects.
+func (r *testRelay) post(t *testing.T, path, body string) {
+	t.Helper()
+	resp, err := http.Post(r.url+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		t.Fatalf("POST %s: unexpected status %d", path, resp.StatusCode)
+	}
+}
+
+// relayctl runs relayctl with args and returns its exit code, stdout and stderr.
+func (r *testRelay) relayctl(vars map[string]string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, env{
+		lookupEnv: func(name string) (string, bool) {
+			v, ok := vars[name]
+			return v, ok
+		},
+		openStorage: func(*config.Config) (storage.Storage, error) { return sharedStorage{Storage: r.store}, nil },
+		stdout:      &stdout,
+		stderr:      &stderr,
+	})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCommands(t *testing.T) {
+	payload := "keysign payload"
+	sum := sha256.Sum256([]byte(payload))
+	hash := hex.EncodeToString(sum[:])
+	// backends returns the flags that select the backend of relayctl
+	backends := map[string]func(r *testRelay) []string{
+		"storage": func(*testRelay) []string { return []string{"--storage-type", "memory"} },
+		"admin":   func(r *testRelay) []string { return []string{"--admin-url", r.url} },
+	}
+	vars := map[string]string{"RELAY_ADMIN_TOKEN": adminToken}
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			r := newTestRelay(t)
+			flags := backend(r)
+			r.post(t, "/s1", `["alice","bob"]`)
+			r.post(t, "/start/s1", `["alice","bob"]`)
+			r.post(t, "/message/s1", `{"session_id":"s1","from":"alice","to":["bob"],"body":"cm91bmQx","hash":"h1","sequence_no":1}`)
+			r.post(t, "/s2", `["carol"]`)
+			r.post(t, "/payload/"+hash, payload)
+
+			tests := []struct {
+				name     string
+				args     []string
+				wantCode int
+				// wantOut are expected in stdout, or in stderr when the command fails
+				wantOut []string
+			}{
+				{name: "sessions", args: []string{"sessions"}, wantOut: []string{"SESSION", "s1", "started", "s2", "joining"}},
+				{name: "session", args: []string{"session", "s1"}, wantOut: []string{"participants:  alice, bob", "started:       alice, bob", "bob"}},
+				{name: "missing session", args: []string{"session", "missing"}, wantCode: exitMissing, wantOut: []string{"not found"}},
+				{name: "payload", args: []string{"payload", hash}, wantOut: []string{payload}},
+				{name: "missing payload", args: []string{"payload", strings.Repeat("0", 64)}, wantCode: exitMissing, wantOut: []string{"not found"}},
+				{name: "invalid payload", args: []string{"payload", "md5:00"}, wantCode: exitFailed},
+				{name: "expire", args: []string{"expire", "s2"}, wantOut: []string{"session s2 expired"}},
+				{name: "expire again", args: []string{"expire", "s2"}, wantCode: exitMissing, wantOut: []string{"not found"}},
+				{name: "expired", args: []string{"session", "s2"}, wantCode: exitMissing},
+				{name: "no command", wantCode: exitUsage, wantOut: []string{"usage: relayctl"}},
+				{name: "unknown command", args: []string{"restart"}, wantCode: exitUsage, wantOut: []string{"usage: relayctl"}},
+				{name: "missing argument", args: []string{"session"}, wantCode: exitUsage, wantOut: []string{"usage: relayctl"}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					code, stdout, stderr := r.relayctl(vars, append(flags, tt.args...)...)
+					if code != tt.wantCode {
+						t.Fatalf("expected exit code %d, got %d, stderr: %s", tt.wantCode, code, stderr)
+					}
+					out := stdout
+					if code != exitOK {
+						out = stderr
+					}
+					for _, want := range tt.wantOut {
+						if !strings.Contains(out, want) {
+							t.Fatalf("expected %q in:\n%s", want, out)
+						}
+					}
+				})
+			}
+			if code, stdout, _ := r.relayctl(vars, append(flags, "sessions")...); code != exitOK || strings.Contains(stdout, "s2") {
+				t.Fatalf("expected the expired session to be gone, got %d:\n%s", code, stdout)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	r := newTestRelay(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	tests := []struct {
+		name     string
+		vars     map[string]string
+		args     []string
+		wantCode int
+		wantErr  string
+	}{
+		{name: "help", args: []string{"-h"}, wantCode: exitOK, wantErr: "usage: relayctl"},
+		{name: "admin url without value", args: []string{"--admin-url"}, wantCode: exitUsage, wantErr: "flag needs an argument: -admin-url"},
+		{name: "invalid config", args: []string{"--port", "http", "sessions"}, wantCode: exitUsage, wantErr: "invalid configuration"},
+		{name: "invalid admin url", args: []string{"--admin-url", "relay", "sessions"}, wantCode: exitFailed, wantErr: "invalid admin url"},
+		{name: "admin without token", args: []string{"--admin-url", r.url, "sessions"}, wantCode: exitFailed, wantErr: "admin.token is required"},
+		{name: "wrong token", vars: map[string]string{"RELAY_ADMIN_TOKEN": "another-admin-token"}, args: []string{"--admin-url", r.url, "sessions"},
+			wantCode: exitFailed, wantErr: "401"},
+		{name: "relay down", vars: map[string]string{"RELAY_ADMIN_TOKEN": adminToken}, args: []string{"--admin-url", down.URL, "sessions"},
+			wantCode: exitFailed, wantErr: "fail to GET /admin/sessions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := r.relayctl(tt.vars, tt.args...)
+			if code != tt.wantCode || !strings.Contains(stderr, tt.wantErr) {
+				t.Fatalf("expected exit code %d and %q, got %d:\n%s", tt.wantCode, tt.wantErr, code, stderr)
+			}
+		})
+	}
+
+	// a storage that can't be opened or inspected fails the command
+	var stderr bytes.Buffer
+	code := run(context.Background(), []string{"sessions"}, env{
+		lookupEnv:   func(string) (string, bool) { return "", false },
+		openStorage: func(*config.Config) (storage.Storage, error) { return uninspectable{Storage: r.store}, nil },
+		stdout:      io.Discard,
+		stderr:      &stderr,
+	})
+	if code != exitFailed || !strings.Contains(stderr.String(), "can't be inspected") {
+		t.Fatalf("expected an uninspectable storage to fail, got %d:\n%s", code, stderr.String())
+	}
+}
+
+// uninspectable hides the Inspector of the storage.
+type uninspectable struct {
+	storage.Storage
+}
+
+func (s uninspectable) Close() error {
+	return nil
+}
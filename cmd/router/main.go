@@ -1,33 +1,115 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/vultisig/vultisig-relay/audit"
 	"github.com/vultisig/vultisig-relay/config"
 	"github.com/vultisig/vultisig-relay/server"
 	"github.com/vultisig/vultisig-relay/storage"
 )
 
+// exit codes of the relay
+const (
+	exitOK            = 0
+	exitStartupFailed = 1
+	exitServerFailed  = 2
+	exitDrainFailed   = 3
+)
+
 func main() {
-	var cfgFile string
-	flag.StringVar(&cfgFile, "config", "config.json", "config file")
-	flag.Parse()
+	os.Exit(run())
+}
 
-	cfg, err := config.LoadConfig(cfgFile)
+func run() int {
+	cfg, opts, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		return exitStartupFailed
+	}
+	if opts.PrintConfig {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cfg.Masked()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitStartupFailed
+		}
+		return exitOK
+	}
+	if opts.File != "" {
+		fmt.Println("loaded config file", opts.File)
 	}
-	store, err := storage.NewRedisStorage(cfg.RedisServer)
+	store, err := storage.New(cfg)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		return exitStartupFailed
 	}
-	s := server.NewServer(cfg.Port, store)
-	if err := s.StartServer(); err != nil {
-		panic(err)
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "fail to close storage", err)
+		}
+	}()
+	s := server.NewServer(cfg, store)
+	if cfg.Admin.Enabled() {
+		auditor, err := audit.Open(cfg.Admin.AuditLog)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitStartupFailed
+		}
+		s.SetAuditLogger(auditor)
 	}
 
-	err = store.Close()
-	if err != nil {
-		panic(err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	servers := 1
+	serverErr := make(chan error, 3)
+	go func() {
+		serverErr <- s.StartServer()
+	}()
+	if cfg.GRPC.Enabled() {
+		servers++
+		go func() {
+			serverErr <- s.StartGRPCServer()
+		}()
+	}
+	if cfg.Metrics.Enabled() {
+		servers++
+		go func() {
+			serverErr <- s.StartMetricsServer()
+		}()
+	}
+	select {
+	case err := <-serverErr:
+		fmt.Fprintln(os.Stderr, "server stopped unexpectedly", err)
+		return exitServerFailed
+	case <-ctx.Done():
+	}
+	stop()
+	// a second signal ends the grace period, a third one terminates the relay immediately
+	drainCtx, stopDrain := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stopDrain()
+	context.AfterFunc(drainCtx, stopDrain)
+	fmt.Println("shutting down")
+	if err := s.StopServer(drainCtx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitDrainFailed
+	}
+	for i := 0; i < servers; i++ {
+		if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, err)
+			return exitServerFailed
+		}
 	}
+	return exitOK
 }
@@ -1,39 +1,293 @@
 package config
 
 import (
-	"encoding/json"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"fmt"
-	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Port             int64       `json:"port"`
-	RedisServer      RedisServer `json:"redis_server"`
-	ConnectionString string      `json:"connection_string"`
+	Port        int64       `json:"port"`
+	TLS         TLS         `json:"tls"`
+	RedisServer RedisServer `json:"redis_server" env:"REDIS"`
+	// ConnectionString is the database of the sql storage, postgres://... or sqlite://path
+	ConnectionString string   `json:"connection_string" secret:"true"`
+	Storage          Storage  `json:"storage"`
+	Shutdown         Shutdown `json:"shutdown"`
+	Admin            Admin    `json:"admin"`
+	GRPC             GRPC     `json:"grpc"`
+	Metrics          Metrics  `json:"metrics"`
+	// ResponseCompression compresses the responses of the clients that send Accept-Encoding
+	ResponseCompression ResponseCompression `json:"response_compression"`
+	Payload             Payload             `json:"payload"`
 }
 
+// MaxRequestBody is the size limit of the request bodies, in bytes.
+const MaxRequestBody = 100 << 20
+
+// Payload configures the keysign payloads.
+type Payload struct {
+	// MaxSize is the size limit of a payload in bytes, at most MaxRequestBody
+	MaxSize int64 `json:"max_size"`
+}
+
+// Compression algorithms of Compression.Algorithm
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Compression configures the compression of stored values, payloads and setup messages can be hundreds of KB.
+type Compression struct {
+	// Algorithm is none (default), gzip or zstd. Values stored with another algorithm stay readable.
+	Algorithm string `json:"algorithm"`
+	// MinSize is the size in bytes from which values are compressed
+	MinSize int64 `json:"min_size"`
+}
+
+// ResponseCompression configures the compression of the responses, zstd or gzip as negotiated with Accept-Encoding.
+type ResponseCompression struct {
+	Enabled bool `json:"enabled"`
+	// MinSize is the size in bytes from which responses are compressed
+	MinSize int64 `json:"min_size"`
+}
+
+// GRPC configures the gRPC API, it is disabled unless a port is set. It is served with the TLS configuration of the relay.
+type GRPC struct {
+	Port int64 `json:"port"`
+}
+
+// Enabled returns true when the gRPC API is configured.
+func (g GRPC) Enabled() bool {
+	return g.Port != 0
+}
+
+// Metrics configures the Prometheus metrics, they are disabled unless a port is set.
+// They are served over plain HTTP on their own port, so they are not exposed on the public relay port.
+type Metrics struct {
+	Port int64 `json:"port"`
+}
+
+// Enabled returns true when the metrics are served.
+func (m Metrics) Enabled() bool {
+	return m.Port != 0
+}
+
+// Admin configures the admin API, it is disabled unless a token or client identities are set.
+type Admin struct {
+	// Token is the bearer token of the admin API
+	Token string `json:"token" secret:"true"`
+	// ClientIdentities are the common names of the client certificates allowed to use the admin API,
+	// the certificates are verified against tls.client_ca_file
+	ClientIdentities []string `json:"client_identities"`
+	// AuditLog is the file audit records are appended to, stdout when empty
+	AuditLog string `json:"audit_log"`
+}
+
+// Enabled returns true when the admin API is configured.
+func (a Admin) Enabled() bool {
+	return a.Token != "" || len(a.ClientIdentities) > 0
+}
+
+// Shutdown configures how the relay drains when it receives SIGTERM or SIGINT.
+type Shutdown struct {
+	// GracePeriod is how long existing sessions keep being served after draining starts,
+	// while new sessions are refused and the relay reports itself as not ready
+	GracePeriod Duration `json:"grace_period"`
+	// Timeout is the deadline for in-flight requests to finish once the listener is closed
+	Timeout Duration `json:"timeout"`
+}
+
+// Duration is a time.Duration that is configured as a string such as "30s".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %s, err: %w", text, err)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// DefaultConfig returns the configuration used for every field that is not set.
+func DefaultConfig() Config {
+	return Config{
+		Port: 8080,
+		Storage: Storage{
+			Type: "redis",
+			Path: "relay.db",
+			Expiration: Expiration{
+				Session: Duration(time.Minute * 5),
+				Value:   Duration(time.Hour),
+			},
+			Compression: Compression{
+				Algorithm: CompressionNone,
+				MinSize:   1024,
+			},
+		},
+		Shutdown: Shutdown{
+			GracePeriod: Duration(time.Second * 5),
+			Timeout:     Duration(time.Second * 20),
+		},
+		ResponseCompression: ResponseCompression{
+			Enabled: true,
+			MinSize: 1024,
+		},
+		Payload: Payload{
+			MaxSize: MaxRequestBody,
+		},
+	}
+}
+
+// Redis deployment modes supported by RedisServer.Mode
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
 type RedisServer struct {
+	// Mode is one of standalone (default), sentinel or cluster
+	Mode     string `json:"mode"`
 	Addr     string `json:"addr"`
 	User     string `json:"user"`
-	Password string `json:"password"`
+	Password string `json:"password" secret:"true"`
 	DB       int    `json:"db"`
+	// Addrs are the sentinel addresses in sentinel mode and the seed nodes in cluster mode, Addr is used when empty
+	Addrs []string `json:"addrs"`
+	// MasterName is the name of the master monitored by the sentinels
+	MasterName       string   `json:"master_name"`
+	SentinelUser     string   `json:"sentinel_user"`
+	SentinelPassword string   `json:"sentinel_password" secret:"true"`
+	TLS              RedisTLS `json:"tls"`
 }
 
-// LoadConfig loads the configuration from a file.
-func LoadConfig(file string) (*Config, error) {
-	f, err := os.Open(file)
+// TLS configures TLS termination in the relay, it is disabled unless both cert_file and key_file are set.
+type TLS struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// MinVersion is the minimum TLS version accepted, 1.2 (default) or 1.3
+	MinVersion string `json:"min_version"`
+	// ClientCAFile enables mTLS, every client has to present a certificate signed by one of these CAs
+	ClientCAFile string `json:"client_ca_file"`
+	// OptionalClientCert verifies client certificates only when they are given, so relay clients without one can
+	// connect while the admin API authenticates with client certificates
+	OptionalClientCert bool `json:"optional_client_cert"`
+}
+
+// Enabled returns true when the relay should serve HTTPS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// RedisTLS configures the TLS connection to redis.
+type RedisTLS struct {
+	Enabled bool `json:"enabled"`
+	// CAFile verifies the redis server certificate, the system pool is used when empty
+	CAFile string `json:"ca_file"`
+	// CertFile and KeyFile are the client certificate, when redis requires one
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	ServerName         string `json:"server_name"`
+	MinVersion         string `json:"min_version"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+// ParseTLSVersion converts a version such as "1.2" to its crypto/tls constant, empty means TLS 1.2.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version %s", version)
+	}
+}
+
+type Storage struct {
+	// Type is the storage backend, redis (default), memory, bolt or sql (uses connection_string)
+	Type string `json:"type"`
+	// Path is the database file of the bolt storage
+	Path       string     `json:"path"`
+	Expiration Expiration `json:"expiration"`
+	// LegacyKeys makes the relay read keys written before keys were namespaced, enable it during rollout only.
+	LegacyKeys  bool        `json:"legacy_keys"`
+	Compression Compression `json:"compression"`
+	Encryption  Encryption  `json:"encryption"`
+}
+
+// Encryption configures the encryption at rest of the stored values, it is disabled unless keys are set.
+type Encryption struct {
+	// Keys are AES-256 keys written as key_id:base64, values are encrypted with the first one,
+	// the others decrypt the values written before the keys were rotated
+	Keys []string `json:"keys" secret:"true"`
+	// KeyFile is a file with one key_id:base64 key per line, its keys come after Keys
+	KeyFile string `json:"key_file"`
+}
+
+// Enabled returns true when encryption keys are configured.
+func (e Encryption) Enabled() bool {
+	return len(e.Keys) > 0 || e.KeyFile != ""
+}
+
+// ParseEncryptionKey parses a key_id:base64 encryption key, the key is 32 bytes for AES-256.
+func ParseEncryptionKey(s string) (string, []byte, error) {
+	id, encoded, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || id == "" {
+		return "", nil, errors.New("encryption key has to be key_id:base64")
+	}
+	if len(id) > 255 {
+		return "", nil, fmt.Errorf("encryption key ID %s is longer than 255 bytes", id)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return nil, fmt.Errorf("fail to open config file %s, err: %w", file, err)
-	}
-	defer func(f *os.File) {
-		err := f.Close()
-		if err != nil {
-			fmt.Println("fail to close file", err)
-		}
-	}(f)
-	var cfg Config
-	if err := json.NewDecoder(f).Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("fail to decode config file %s, err: %w", file, err)
+		return "", nil, fmt.Errorf("encryption key %s is not base64, err: %w", id, err)
+	}
+	if len(key) != 32 {
+		return "", nil, fmt.Errorf("encryption key %s has %d bytes instead of 32", id, len(key))
+	}
+	return id, key, nil
+}
+
+// Expiration is how long the relay keeps data without activity.
+type Expiration struct {
+	// Session applies to session participants, markers and messages, default 5m
+	Session Duration `json:"session"`
+	// Value applies to payloads, setup messages and keysign results, default 1h
+	Value Duration `json:"value"`
+}
+
+// SessionTTL returns the session expiration, or the default when it is not set.
+func (e Expiration) SessionTTL() time.Duration {
+	if e.Session <= 0 {
+		return time.Minute * 5
+	}
+	return time.Duration(e.Session)
+}
+
+// ValueTTL returns the value expiration, or the default when it is not set.
+func (e Expiration) ValueTTL() time.Duration {
+	if e.Value <= 0 {
+		return time.Hour
+	}
+	return time.Duration(e.Value)
+}
+
+// LoadConfig loads the configuration from a JSON or YAML file, on top of the defaults.
+func LoadConfig(file string) (*Config, error) {
+	cfg := DefaultConfig()
+	if err := loadFile(file, &cfg); err != nil {
+		return nil, err
 	}
 	return &cfg, nil
 }
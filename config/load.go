@@ -0,0 +1,348 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is the prefix of every environment variable read by the relay, e.g. RELAY_REDIS_ADDR.
+const envPrefix = "RELAY"
+
+// defaultConfigFile is used when no config file is given, it is optional.
+const defaultConfigFile = "config.json"
+
+// secretMask replaces the value of secret fields when the configuration is printed.
+const secretMask = "******"
+
+// Options are the command line options that are not part of the configuration.
+type Options struct {
+	// File is the config file that was loaded, empty when none was found
+	File string
+	// PrintConfig asks to print the effective configuration and exit
+	PrintConfig bool
+	// Args are the arguments left after the flags
+	Args []string
+}
+
+// field is a configuration value that can be set from the environment and the command line.
+type field struct {
+	path   string
+	env    string
+	flag   string
+	secret bool
+	value  reflect.Value
+}
+
+// fields walks the configuration and returns every leaf field.
+// Names come from the json tags, an env tag overrides the name of a field in environment variables and flags.
+func fields(v reflect.Value, path, env string) []field {
+	var result []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		envName := sf.Tag.Get("env")
+		if envName == "" {
+			envName = strings.ToUpper(name)
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		fieldEnv := env + "_" + envName
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && !isTextUnmarshaler(fv) {
+			result = append(result, fields(fv, fieldPath, fieldEnv)...)
+			continue
+		}
+		result = append(result, field{
+			path:   fieldPath,
+			env:    fieldEnv,
+			flag:   strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(fieldEnv, envPrefix+"_")), "_", "-"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  fv,
+		})
+	}
+	return result
+}
+
+func isTextUnmarshaler(v reflect.Value) bool {
+	_, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	return ok
+}
+
+func (f field) isBool() bool {
+	return f.value.Kind() == reflect.Bool
+}
+
+// set parses the string representation of the field value.
+func (f field) set(raw string) error {
+	if u, ok := f.value.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid bool %s", raw)
+		}
+		f.value.SetBool(v)
+	case reflect.Int, reflect.Int64:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %s", raw)
+		}
+		f.value.SetInt(v)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// Load builds the configuration in layers: defaults, then the config file (JSON or YAML),
+// then RELAY_* environment variables, then command line flags. Every invalid field is reported at once.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, Options, error) {
+	cfg := DefaultConfig()
+	var opts Options
+	cfgFields := fields(reflect.ValueOf(&cfg).Elem(), "", envPrefix)
+
+	fs := flag.NewFlagSet("relay", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", "", fmt.Sprintf("config file, JSON or YAML (env %s_CONFIG, default %s)", envPrefix, defaultConfigFile))
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration with secrets masked and exit")
+	flagValues := make(map[string]string)
+	for _, f := range cfgFields {
+		name := f.flag
+		usage := fmt.Sprintf("%s (env %s)", f.path, f.env)
+		record := func(v string) error {
+			flagValues[name] = v
+			return nil
+		}
+		if f.isBool() {
+			fs.BoolFunc(name, usage, func(v string) error { return record(v) })
+			continue
+		}
+		fs.Func(name, usage, record)
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return nil, opts, err
+	}
+	opts.Args = fs.Args()
+
+	file := *configFile
+	explicit := file != ""
+	if !explicit {
+		file, explicit = lookupEnv(envPrefix + "_CONFIG")
+	}
+	if file == "" {
+		file = defaultConfigFile
+	}
+	if err := loadFile(file, &cfg); err != nil {
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			return nil, opts, err
+		}
+	} else {
+		opts.File = file
+	}
+
+	var problems []error
+	for _, f := range cfgFields {
+		if v, ok := lookupEnv(f.env); ok {
+			if err := f.set(v); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %s from %s", f.path, err, f.env))
+			}
+		}
+	}
+	for _, f := range cfgFields {
+		if v, ok := flagValues[f.flag]; ok {
+			if err := f.set(v); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %s from -%s", f.path, err, f.flag))
+			}
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, err)
+	}
+	if len(problems) > 0 {
+		return nil, opts, fmt.Errorf("invalid configuration:\n%w", errors.Join(problems...))
+	}
+	return &cfg, opts, nil
+}
+
+// loadFile decodes a JSON or YAML config file on top of the given configuration.
+func loadFile(file string, cfg *Config) error {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("fail to open config file %s, err: %w", file, err)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		// YAML is converted to JSON so both formats share the json tags
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(buf, &raw); err != nil {
+			return fmt.Errorf("fail to decode config file %s, err: %w", file, err)
+		}
+		if buf, err = json.Marshal(raw); err != nil {
+			return fmt.Errorf("fail to decode config file %s, err: %w", file, err)
+		}
+	}
+	if err := json.Unmarshal(buf, cfg); err != nil {
+		return fmt.Errorf("fail to decode config file %s, err: %w", file, err)
+	}
+	return nil
+}
+
+// Masked returns a copy of the configuration with every secret replaced, so it can be printed.
+func (c Config) Masked() Config {
+	masked := c
+	for _, f := range fields(reflect.ValueOf(&masked).Elem(), "", envPrefix) {
+		if !f.secret {
+			continue
+		}
+		switch {
+		case f.value.Kind() == reflect.String && f.value.String() != "":
+			f.value.SetString(secretMask)
+		case f.value.Kind() == reflect.Slice && f.value.Len() > 0:
+			mask := make([]string, f.value.Len())
+			for i := range mask {
+				mask[i] = secretMask
+			}
+			f.value.Set(reflect.ValueOf(mask))
+		}
+	}
+	return masked
+}
+
+// Validate checks every field and reports all the invalid ones.
+func (c Config) Validate() error {
+	var problems []error
+	if c.Port <= 0 || c.Port > 65535 {
+		problems = append(problems, fmt.Errorf("port: %d is not a valid port", c.Port))
+	}
+	if c.GRPC.Port < 0 || c.GRPC.Port > 65535 {
+		problems = append(problems, fmt.Errorf("grpc.port: %d is not a valid port", c.GRPC.Port))
+	} else if c.GRPC.Enabled() && c.GRPC.Port == c.Port {
+		problems = append(problems, errors.New("grpc.port: has to differ from port"))
+	}
+	if c.Metrics.Port < 0 || c.Metrics.Port > 65535 {
+		problems = append(problems, fmt.Errorf("metrics.port: %d is not a valid port", c.Metrics.Port))
+	} else if c.Metrics.Enabled() && (c.Metrics.Port == c.Port || c.Metrics.Port == c.GRPC.Port) {
+		problems = append(problems, errors.New("metrics.port: has to differ from port and grpc.port"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problems = append(problems, errors.New("tls: cert_file and key_file have to be set together"))
+	}
+	if _, err := ParseTLSVersion(c.TLS.MinVersion); err != nil {
+		problems = append(problems, fmt.Errorf("tls.min_version: %w", err))
+	}
+	if c.TLS.ClientCAFile != "" && !c.TLS.Enabled() {
+		problems = append(problems, errors.New("tls.client_ca_file: requires cert_file and key_file"))
+	}
+	if c.TLS.OptionalClientCert && c.TLS.ClientCAFile == "" {
+		problems = append(problems, errors.New("tls.optional_client_cert: requires client_ca_file"))
+	}
+	switch c.RedisServer.Mode {
+	case "", RedisModeStandalone:
+	case RedisModeSentinel:
+		if c.RedisServer.MasterName == "" {
+			problems = append(problems, errors.New("redis_server.master_name: is required in sentinel mode"))
+		}
+	case RedisModeCluster:
+		if c.RedisServer.DB != 0 {
+			problems = append(problems, errors.New("redis_server.db: has to be 0 in cluster mode"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("redis_server.mode: unknown mode %s", c.RedisServer.Mode))
+	}
+	if _, err := ParseTLSVersion(c.RedisServer.TLS.MinVersion); err != nil {
+		problems = append(problems, fmt.Errorf("redis_server.tls.min_version: %w", err))
+	}
+	switch c.Storage.Type {
+	case "", "redis", "memory":
+	case "sql":
+		if c.ConnectionString == "" {
+			problems = append(problems, errors.New("connection_string: is required by the sql storage"))
+		}
+	case "bolt":
+		if c.Storage.Path == "" {
+			problems = append(problems, errors.New("storage.path: is required by the bolt storage"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("storage.type: unknown storage type %s", c.Storage.Type))
+	}
+	// redis expires keys with a resolution of one second
+	if c.Storage.Expiration.Session < 0 || (c.Storage.Expiration.Session > 0 && c.Storage.Expiration.Session < Duration(time.Second)) {
+		problems = append(problems, errors.New("storage.expiration.session: has to be at least 1s"))
+	}
+	if c.Storage.Expiration.Value < 0 || (c.Storage.Expiration.Value > 0 && c.Storage.Expiration.Value < Duration(time.Second)) {
+		problems = append(problems, errors.New("storage.expiration.value: has to be at least 1s"))
+	}
+	switch c.Storage.Compression.Algorithm {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		problems = append(problems, fmt.Errorf("storage.compression.algorithm: unknown algorithm %s", c.Storage.Compression.Algorithm))
+	}
+	if c.Storage.Compression.MinSize < 0 {
+		problems = append(problems, errors.New("storage.compression.min_size: can't be negative"))
+	}
+	if c.ResponseCompression.MinSize < 0 {
+		problems = append(problems, errors.New("response_compression.min_size: can't be negative"))
+	}
+	if c.Payload.MaxSize <= 0 || c.Payload.MaxSize > MaxRequestBody {
+		problems = append(problems, fmt.Errorf("payload.max_size: has to be between 1 and %d bytes", MaxRequestBody))
+	}
+	ids := make(map[string]bool)
+	for _, k := range c.Storage.Encryption.Keys {
+		id, _, err := ParseEncryptionKey(k)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("storage.encryption.keys: %w", err))
+			continue
+		}
+		if ids[id] {
+			problems = append(problems, fmt.Errorf("storage.encryption.keys: duplicate key ID %s", id))
+		}
+		ids[id] = true
+	}
+	if c.Admin.Token != "" && len(c.Admin.Token) < 16 {
+		problems = append(problems, errors.New("admin.token: has to be at least 16 characters"))
+	}
+	if len(c.Admin.ClientIdentities) > 0 && c.TLS.ClientCAFile == "" {
+		problems = append(problems, errors.New("admin.client_identities: requires tls.client_ca_file"))
+	}
+	if c.Shutdown.GracePeriod < 0 {
+		problems = append(problems, errors.New("shutdown.grace_period: can't be negative"))
+	}
+	if c.Shutdown.Timeout <= 0 {
+		problems = append(problems, errors.New("shutdown.timeout: has to be positive"))
+	}
+	return errors.Join(problems...)
+}
@@ -0,0 +1,38 @@
+// Package metrics defines the Prometheus metrics of the relay, they are served at /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// StorageCompressedValues counts the values stored compressed, by algorithm.
+	StorageCompressedValues = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "relay",
+		Subsystem: "storage",
+		Name:      "compressed_values_total",
+		Help:      "Values stored compressed.",
+	}, []string{"algorithm"})
+	// StorageCompressionSavedBytes counts the bytes saved in storage by compressing values, by algorithm.
+	StorageCompressionSavedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "relay",
+		Subsystem: "storage",
+		Name:      "compression_saved_bytes_total",
+		Help:      "Bytes saved in storage by compressing values.",
+	}, []string{"algorithm"})
+	// CompressedResponses counts the responses sent compressed, by content encoding.
+	CompressedResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "relay",
+		Subsystem: "http",
+		Name:      "compressed_responses_total",
+		Help:      "Responses sent compressed.",
+	}, []string{"encoding"})
+	// ResponseCompressionSavedBytes counts the bytes saved by compressing responses, by content encoding.
+	ResponseCompressionSavedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "relay",
+		Subsystem: "http",
+		Name:      "response_compression_saved_bytes_total",
+		Help:      "Bytes saved by compressing responses.",
+	}, []string{"encoding"})
+)
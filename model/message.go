@@ -1,15 +1,68 @@
 package model
 
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
 // Message is a struct that represents a message sent from one user to another.
+// The body is opaque bytes, JSON encodes it as a string like before binary bodies were supported,
+// and in body_base64 when it isn't valid UTF-8.
 type Message struct {
+	SessionID  string   `json:"session_id,omitempty"`
+	From       string   `json:"from,omitempty"`
+	To         []string `json:"to,omitempty"`
+	Body       []byte   `json:"body,omitempty"`
+	Hash       string   `json:"hash"`
+	SequenceNo uint64   `json:"sequence_no"`
+}
+
+// messageJSON is the JSON encoding of a Message, the fields keep their order so stored messages encode the same.
+type messageJSON struct {
 	SessionID  string   `json:"session_id,omitempty"`
 	From       string   `json:"from,omitempty"`
 	To         []string `json:"to,omitempty"`
 	Body       string   `json:"body,omitempty"`
+	BodyBase64 []byte   `json:"body_base64,omitempty"`
 	Hash       string   `json:"hash"`
 	SequenceNo uint64   `json:"sequence_no"`
 }
 
+func (m Message) MarshalJSON() ([]byte, error) {
+	v := messageJSON{
+		SessionID:  m.SessionID,
+		From:       m.From,
+		To:         m.To,
+		Hash:       m.Hash,
+		SequenceNo: m.SequenceNo,
+	}
+	if utf8.Valid(m.Body) {
+		v.Body = string(m.Body)
+	} else {
+		v.BodyBase64 = m.Body
+	}
+	return json.Marshal(v)
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var v messageJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = Message{
+		SessionID:  v.SessionID,
+		From:       v.From,
+		To:         v.To,
+		Body:       v.BodyBase64,
+		Hash:       v.Hash,
+		SequenceNo: v.SequenceNo,
+	}
+	if v.Body != "" {
+		m.Body = []byte(v.Body)
+	}
+	return nil
+}
+
 type Session struct {
 	SessionID    string   `json:"session_id,omitempty"`
 	Participants []string `json:"participants,omitempty"`
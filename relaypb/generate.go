@@ -0,0 +1,4 @@
+// Package relaypb holds the protobuf messages and the gRPC service of the relay, generated from proto/ with buf.
+package relaypb
+
+//go:generate buf generate ../proto --template ../buf.gen.yaml --output ..
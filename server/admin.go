@@ -0,0 +1,402 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vultisig/vultisig-relay/audit"
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+const (
+	defaultAdminPageSize = 50
+	maxAdminPageSize     = 500
+	// context keys set by the admin middlewares and handlers
+	adminActorKey  = "admin_actor"
+	adminDetailKey = "admin_detail"
+)
+
+// SessionPage is a page of sessions returned by the admin API.
+type SessionPage struct {
+	Sessions []storage.SessionInfo `json:"sessions"`
+	// NextCursor is passed as cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// UserPage is a page of users returned by the admin API.
+type UserPage struct {
+	Users      []model.User `json:"users"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// Toggles are the runtime switches of the relay.
+type Toggles struct {
+	// Maintenance refuses every relay request
+	Maintenance bool `json:"maintenance"`
+	// ReadOnly refuses the relay requests that write
+	ReadOnly bool `json:"read_only"`
+}
+
+func (s *Server) registerAdminRoutes(g *echo.Group) {
+	g.GET("/sessions", s.AdminListSessions)
+	g.GET("/sessions/:sessionID", s.AdminGetSession)
+	g.DELETE("/sessions/:sessionID", s.AdminDeleteSession)
+	g.GET("/users", s.AdminListUsers)
+	g.POST("/users", s.AdminCreateUser)
+	g.GET("/users/:id", s.AdminGetUser)
+	g.PUT("/users/:id", s.AdminUpdateUser)
+	g.DELETE("/users/:id", s.AdminDeleteUser)
+	g.GET("/toggles", s.AdminGetToggles)
+	g.PUT("/toggles", s.AdminSetToggles)
+}
+
+// auditAdmin records every admin request, including the ones that fail authentication.
+func (s *Server) auditAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		status := c.Response().Status
+		var he *echo.HTTPError
+		switch {
+		case errors.As(err, &he):
+			status = he.Code
+		case err != nil:
+			status = http.StatusInternalServerError
+		}
+		actor, _ := c.Get(adminActorKey).(string)
+		if actor == "" {
+			actor = "anonymous"
+		}
+		detail, _ := c.Get(adminDetailKey).(string)
+		record := audit.Record{
+			Actor:    actor,
+			Action:   c.Request().Method + " " + c.Path(),
+			Target:   strings.Join(c.ParamValues(), "/"),
+			Detail:   detail,
+			RemoteIP: c.RealIP(),
+			Status:   status,
+		}
+		if logErr := s.auditor.Log(record); logErr != nil {
+			c.Logger().Error(logErr)
+		}
+		return err
+	}
+}
+
+// authenticateAdmin accepts the admin token as a bearer token, or a verified client certificate
+// whose common name is one of the admin client identities.
+func (s *Server) authenticateAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		actor := s.adminActor(c.Request())
+		if actor == "" {
+			c.Response().Header().Set("WWW-Authenticate", "Bearer")
+			return c.NoContent(http.StatusUnauthorized)
+		}
+		c.Set(adminActorKey, actor)
+		return next(c)
+	}
+}
+
+func (s *Server) adminActor(r *http.Request) string {
+	if s.admin.Token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.admin.Token)) == 1 {
+			return "token"
+		}
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	for _, identity := range s.admin.ClientIdentities {
+		if cn != "" && cn == identity {
+			return "cert:" + cn
+		}
+	}
+	return ""
+}
+
+// guardToggles refuses relay requests while the relay is in maintenance, and writes while it is read-only.
+func (s *Server) guardToggles(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if s.maintenance.Load() {
+			return unavailable(CodeMaintenance, "relay is in maintenance")
+		}
+		if s.readOnly.Load() {
+			switch c.Request().Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				return unavailable(CodeReadOnly, "relay is read-only")
+			}
+		}
+		return next(c)
+	}
+}
+
+// pageParams parses the cursor and limit query parameters.
+func pageParams(c echo.Context) (string, int, error) {
+	limit := defaultAdminPageSize
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return "", 0, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = min(n, maxAdminPageSize)
+	}
+	return c.QueryParam("cursor"), limit, nil
+}
+
+// AdminListSessions returns a page of sessions ordered by ID, the cursor is the last session ID of the previous page.
+func (s *Server) AdminListSessions(c echo.Context) error {
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	inspector, ok := storage.AsInspector(s.s)
+	if !ok {
+		return c.NoContent(http.StatusNotImplemented)
+	}
+	ctx := c.Request().Context()
+	sessionIDs, err := storage.ListSessions(ctx, inspector)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	page := SessionPage{Sessions: []storage.SessionInfo{}}
+	for i, sessionID := range sessionIDs {
+		if sessionID <= cursor {
+			continue
+		}
+		if len(page.Sessions) == limit {
+			page.NextCursor = sessionIDs[i-1]
+			break
+		}
+		info, err := storage.InspectSession(ctx, s.s, inspector, sessionID)
+		if errors.Is(err, storage.ErrNotFound) {
+			// expired between listing and inspecting
+			continue
+		}
+		if err != nil {
+			c.Logger().Error(err)
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		page.Sessions = append(page.Sessions, info)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// AdminGetSession returns the participants, state, TTL and inboxes of a session.
+func (s *Server) AdminGetSession(c echo.Context) error {
+	inspector, ok := storage.AsInspector(s.s)
+	if !ok {
+		return c.NoContent(http.StatusNotImplemented)
+	}
+	info, err := storage.InspectSession(c.Request().Context(), s.s, inspector, c.Param("sessionID"))
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	return c.JSON(http.StatusOK, info)
+}
+
+// AdminDeleteSession deletes a session and everything it tracks.
+func (s *Server) AdminDeleteSession(c echo.Context) error {
+	sessionID := c.Param("sessionID")
+	ctx := c.Request().Context()
+	if inspector, ok := storage.AsInspector(s.s); ok {
+		_, err := storage.InspectSession(ctx, s.s, inspector, sessionID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		if err != nil {
+			c.Logger().Error(err)
+			return c.NoContent(http.StatusInternalServerError)
+		}
+	}
+	if err := storage.ReleaseSessionPayloads(ctx, s.s, sessionID); err != nil {
+		c.Logger().Errorf("fail to release payloads of session %s, err: %s", sessionID, err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	if err := s.s.DeleteSession(ctx, sessionID); err != nil {
+		c.Logger().Errorf("fail to delete session %s, err: %s", sessionID, err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// AdminListUsers returns a page of users ordered by ID, the cursor is the last user ID of the previous page.
+func (s *Server) AdminListUsers(c echo.Context) error {
+	cursor, limit, err := pageParams(c)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	var afterID int64
+	if cursor != "" {
+		if afterID, err = strconv.ParseInt(cursor, 10, 64); err != nil {
+			return c.NoContent(http.StatusBadRequest)
+		}
+	}
+	users, ok := storage.AsUserStore(s.s)
+	if !ok {
+		return c.NoContent(http.StatusNotImplemented)
+	}
+	// one more user tells whether there is a next page
+	list, err := users.ListUsers(c.Request().Context(), afterID, limit+1)
+	if err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	page := UserPage{Users: list}
+	if len(list) > limit {
+		page.Users = list[:limit]
+		page.NextCursor = strconv.FormatInt(list[limit-1].ID, 10)
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+// AdminCreateUser creates a user, an API key is generated when none is given.
+func (s *Server) AdminCreateUser(c echo.Context) error {
+	users, ok := storage.AsUserStore(s.s)
+	if !ok {
+		return c.NoContent(http.StatusNotImplemented)
+	}
+	var user model.User
+	if err := c.Bind(&user); err != nil || user.ID != 0 {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	if user.APIKey == "" {
+		key, err := newAPIKey()
+		if err != nil {
+			c.Logger().Error(err)
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		user.APIKey = key
+	}
+	if !user.CreatedAt.Valid {
+		user.CreatedAt.Time, user.CreatedAt.Valid = time.Now().UTC(), true
+	}
+	if err := users.SaveUser(c.Request().Context(), &user); err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	c.Set(adminDetailKey, fmt.Sprintf("created user %d", user.ID))
+	return c.JSON(http.StatusCreated, user)
+}
+
+// AdminGetUser returns a user.
+func (s *Server) AdminGetUser(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	users, ok := storage.AsUserStore(s.s)
+	if !ok {
+		return c.NoContent(http.StatusNotImplemented)
+	}
+	user, err := users.GetUser(c.Request().Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	return c.JSON(http.StatusOK, user)
+}
+
+// AdminUpdateUser replaces a user.
+func (s *Server) AdminUpdateUser(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	users, ok := storage.AsUserStore(s.s)
+	if !ok {
+		return c.NoContent(http.StatusNotImplemented)
+	}
+	var user model.User
+	if err := c.Bind(&user); err != nil || (user.ID != 0 && user.ID != id) || user.APIKey == "" {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	user.ID = id
+	err = users.SaveUser(c.Request().Context(), &user)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	c.Set(adminDetailKey, fmt.Sprintf("is_paid=%t no_of_vaults=%d", user.IsPaid, user.NoOfVaults))
+	return c.JSON(http.StatusOK, user)
+}
+
+// AdminDeleteUser deletes a user.
+func (s *Server) AdminDeleteUser(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	users, ok := storage.AsUserStore(s.s)
+	if !ok {
+		return c.NoContent(http.StatusNotImplemented)
+	}
+	err = users.DeleteUser(c.Request().Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// AdminGetToggles returns the runtime toggles.
+func (s *Server) AdminGetToggles(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.toggles())
+}
+
+// AdminSetToggles changes the runtime toggles, the toggles missing from the request are left unchanged.
+func (s *Server) AdminSetToggles(c echo.Context) error {
+	var req struct {
+		Maintenance *bool `json:"maintenance"`
+		ReadOnly    *bool `json:"read_only"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+	if req.Maintenance != nil {
+		s.maintenance.Store(*req.Maintenance)
+	}
+	if req.ReadOnly != nil {
+		s.readOnly.Store(*req.ReadOnly)
+	}
+	toggles := s.toggles()
+	c.Set(adminDetailKey, fmt.Sprintf("maintenance=%t read_only=%t", toggles.Maintenance, toggles.ReadOnly))
+	return c.JSON(http.StatusOK, toggles)
+}
+
+func (s *Server) toggles() Toggles {
+	return Toggles{Maintenance: s.maintenance.Load(), ReadOnly: s.readOnly.Load()}
+}
+
+func newAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("fail to generate api key, err: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
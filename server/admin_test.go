@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vultisig/vultisig-relay/audit"
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+const adminToken = "0123456789abcdef0123"
+
+type adminRelay struct {
+	handler http.Handler
+	store   storage.Storage
+	audit   *bytes.Buffer
+}
+
+func newAdminRelay(t *testing.T) *adminRelay {
+	t.Helper()
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatalf("fail to create storage, err: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	cfg := config.DefaultConfig()
+	cfg.Admin = config.Admin{Token: adminToken, ClientIdentities: []string{"ops"}}
+	s := NewServer(&cfg, store)
+	var buf bytes.Buffer
+	s.SetAuditLogger(audit.New(&buf))
+	handler := s.Handler()
+	s.e.Logger.SetOutput(io.Discard)
+	return &adminRelay{handler: validating(t, handler), store: store, audit: &buf}
+}
+
+// do serves the request, authenticated with the admin token when auth is true.
+func (r *adminRelay) do(t *testing.T, method, path, body string, auth bool) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if auth {
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+	}
+	rec := httptest.NewRecorder()
+	r.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func (r *adminRelay) records(t *testing.T) []audit.Record {
+	t.Helper()
+	var records []audit.Record
+	dec := json.NewDecoder(bytes.NewReader(r.audit.Bytes()))
+	for dec.More() {
+		var record audit.Record
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("fail to decode audit record, err: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAdminAuth(t *testing.T) {
+	r := newAdminRelay(t)
+	withCert := func(cn string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/admin/toggles", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}}
+		return req
+	}
+	tests := []struct {
+		name      string
+		req       *http.Request
+		wantCode  int
+		wantActor string
+	}{
+		{name: "no credential", req: httptest.NewRequest(http.MethodGet, "/admin/toggles", nil), wantCode: http.StatusUnauthorized, wantActor: "anonymous"},
+		{name: "wrong token", req: func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/admin/toggles", nil)
+			req.Header.Set("Authorization", "Bearer "+adminToken+"x")
+			return req
+		}(), wantCode: http.StatusUnauthorized, wantActor: "anonymous"},
+		{name: "token", req: func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/admin/toggles", nil)
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+			return req
+		}(), wantCode: http.StatusOK, wantActor: "token"},
+		{name: "client identity", req: withCert("ops"), wantCode: http.StatusOK, wantActor: "cert:ops"},
+		{name: "unknown client identity", req: withCert("dev"), wantCode: http.StatusUnauthorized, wantActor: "anonymous"},
+		{name: "no client certificate", req: func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/admin/toggles", nil)
+			req.TLS = &tls.ConnectionState{}
+			return req
+		}(), wantCode: http.StatusUnauthorized, wantActor: "anonymous"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.handler.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+			records := r.records(t)
+			if len(records) != i+1 {
+				t.Fatalf("expected %d audit records, got %d", i+1, len(records))
+			}
+			got := records[i]
+			if got.Actor != tt.wantActor || got.Status != tt.wantCode || got.Action != "GET /admin/toggles" {
+				t.Fatalf("unexpected audit record %+v", got)
+			}
+		})
+	}
+}
+
+func TestAdminSessions(t *testing.T) {
+	r := newAdminRelay(t)
+	for _, sessionID := range []string{"s1", "s2", "s3"} {
+		if rec := r.do(t, http.MethodPost, "/"+sessionID, `["a","b"]`, false); rec.Code != http.StatusCreated {
+			t.Fatalf("fail to create session %s, status: %d", sessionID, rec.Code)
+		}
+	}
+
+	var ids []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		rec := r.do(t, http.MethodGet, "/admin/sessions?limit=2&cursor="+cursor, "", true)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("fail to list sessions, status: %d", rec.Code)
+		}
+		var page SessionPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		for _, info := range page.Sessions {
+			ids = append(ids, info.SessionID)
+		}
+		if page.NextCursor == "" {
+			if pages != 1 {
+				t.Fatalf("expected 2 pages, got %d", pages+1)
+			}
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if strings.Join(ids, ",") != "s1,s2,s3" {
+		t.Fatalf("unexpected sessions %v", ids)
+	}
+
+	rec := r.do(t, http.MethodGet, "/admin/sessions/s2", "", true)
+	var info storage.SessionInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("fail to inspect session, status: %d, err: %v", rec.Code, err)
+	}
+	if info.State != storage.SessionStateJoining || len(info.Participants) != 2 {
+		t.Fatalf("unexpected session %+v", info)
+	}
+	if rec := r.do(t, http.MethodDelete, "/admin/sessions/s2", "", true); rec.Code != http.StatusOK {
+		t.Fatalf("fail to delete session, status: %d", rec.Code)
+	}
+	if rec := r.do(t, http.MethodGet, "/admin/sessions/s2", "", true); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected deleted session to be gone, status: %d", rec.Code)
+	}
+	if rec := r.do(t, http.MethodDelete, "/admin/sessions/s2", "", true); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting a missing session, status: %d", rec.Code)
+	}
+	records := r.records(t)
+	deleted := records[len(records)-3]
+	if deleted.Action != "DELETE /admin/sessions/:sessionID" || deleted.Target != "s2" || deleted.Status != http.StatusOK {
+		t.Fatalf("unexpected audit record %+v", deleted)
+	}
+}
+
+func TestAdminUsers(t *testing.T) {
+	r := newAdminRelay(t)
+	rec := r.do(t, http.MethodPost, "/admin/users", `{"no_of_vaults":1}`, true)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("fail to create user, status: %d", rec.Code)
+	}
+	var user model.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == 0 || user.APIKey == "" || !user.CreatedAt.Valid {
+		t.Fatalf("expected id, api key and creation time to be set, got %+v", user)
+	}
+	if strings.Contains(r.audit.String(), user.APIKey) {
+		t.Fatal("api key must not be audited")
+	}
+
+	user.IsPaid = true
+	buf, _ := json.Marshal(user)
+	path := "/admin/users/" + jsonNumber(user.ID)
+	if rec := r.do(t, http.MethodPut, path, string(buf), true); rec.Code != http.StatusOK {
+		t.Fatalf("fail to update user, status: %d", rec.Code)
+	}
+	rec = r.do(t, http.MethodGet, path, "", true)
+	var got model.User
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || !got.IsValid() {
+		t.Fatalf("expected a valid user, got %+v, err: %v", got, err)
+	}
+	rec = r.do(t, http.MethodGet, "/admin/users", "", true)
+	var page UserPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Users) != 1 {
+		t.Fatalf("expected one user, got %s", rec.Body)
+	}
+	if rec := r.do(t, http.MethodDelete, path, "", true); rec.Code != http.StatusOK {
+		t.Fatalf("fail to delete user, status: %d", rec.Code)
+	}
+	if rec := r.do(t, http.MethodPut, path, string(buf), true); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 updating a deleted user, status: %d", rec.Code)
+	}
+}
+
+func TestAdminToggles(t *testing.T) {
+	r := newAdminRelay(t)
+	if rec := r.do(t, http.MethodPost, "/s1", `["a"]`, false); rec.Code != http.StatusCreated {
+		t.Fatalf("fail to create session, status: %d", rec.Code)
+	}
+
+	r.do(t, http.MethodPut, "/admin/toggles", `{"read_only":true}`, true)
+	if rec := r.do(t, http.MethodPost, "/s1", `["b"]`, false); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected writes to be refused while read-only, status: %d", rec.Code)
+	}
+	if rec := r.do(t, http.MethodGet, "/s1", "", false); rec.Code != http.StatusOK {
+		t.Fatalf("expected reads to be served while read-only, status: %d", rec.Code)
+	}
+
+	r.do(t, http.MethodPut, "/admin/toggles", `{"maintenance":true}`, true)
+	if rec := r.do(t, http.MethodGet, "/s1", "", false); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected reads to be refused in maintenance, status: %d", rec.Code)
+	}
+	if rec := r.do(t, http.MethodGet, "/ping", "", false); rec.Code != http.StatusOK {
+		t.Fatalf("expected ping to be served in maintenance, status: %d", rec.Code)
+	}
+	rec := r.do(t, http.MethodGet, "/admin/toggles", "", true)
+	var toggles Toggles
+	if err := json.Unmarshal(rec.Body.Bytes(), &toggles); err != nil || !toggles.Maintenance || !toggles.ReadOnly {
+		t.Fatalf("unexpected toggles %s", rec.Body)
+	}
+
+	r.do(t, http.MethodPut, "/admin/toggles", `{"maintenance":false,"read_only":false}`, true)
+	if rec := r.do(t, http.MethodPost, "/s1", `["b"]`, false); rec.Code != http.StatusCreated {
+		t.Fatalf("expected writes to be served again, status: %d", rec.Code)
+	}
+}
+
+func TestAdminDisabled(t *testing.T) {
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	cfg := config.DefaultConfig()
+	s := NewServer(&cfg, store)
+	req := httptest.NewRequest(http.MethodGet, "/admin/toggles", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	// the path falls through to the relay routes
+	if strings.Contains(rec.Body.String(), "maintenance") {
+		t.Fatalf("expected the admin api to be disabled without credentials configured, got %s", rec.Body)
+	}
+}
+
+func jsonNumber(n int64) string {
+	buf, _ := json.Marshal(n)
+	return string(buf)
+}
@@ -0,0 +1,539 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// ceremonyTimeout bounds every wait of a simulated device.
+const ceremonyTimeout = time.Second * 10
+
+// pollInterval is how often a simulated device polls the relay.
+const pollInterval = time.Millisecond * 10
+
+// relay is a Server with in-memory storage, listening on an httptest server.
+type relay struct {
+	url   string
+	store storage.Storage
+}
+
+func newRelay(t *testing.T) *relay {
+	t.Helper()
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatalf("fail to create storage, err: %v", err)
+	}
+	cfg := config.DefaultConfig()
+	s := NewServer(&cfg, store)
+	handler := s.Handler()
+	s.e.Logger.SetOutput(io.Discard)
+	ts := httptest.NewServer(validating(t, handler))
+	t.Cleanup(func() {
+		ts.Close()
+		_ = store.Close()
+	})
+	return &relay{url: ts.URL, store: store}
+}
+
+// device is a simulated vault device taking part in a ceremony.
+type device struct {
+	id     string
+	relay  *relay
+	client *http.Client
+}
+
+func newDevices(r *relay, ids ...string) []*device {
+	devices := make([]*device, 0, len(ids))
+	for _, id := range ids {
+		devices = append(devices, &device{id: id, relay: r, client: &http.Client{Timeout: time.Second * 5}})
+	}
+	return devices
+}
+
+func ids(devices []*device) []string {
+	result := make([]string, 0, len(devices))
+	for _, d := range devices {
+		result = append(result, d.id)
+	}
+	return result
+}
+
+// do sends a request to the relay and returns the status and body of the response.
+func (d *device) do(method, path, messageID string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, d.relay.url+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if messageID != "" {
+		req.Header.Set("message_id", messageID)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, buf, err
+}
+
+// expect sends a request and fails unless the relay answers with the given status.
+func (d *device) expect(status int, method, path, messageID string, body []byte) ([]byte, error) {
+	code, buf, err := d.do(method, path, messageID, body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if code != status {
+		return nil, fmt.Errorf("%s %s: expected status %d, got %d", method, path, status, code)
+	}
+	return buf, nil
+}
+
+func (d *device) expectJSON(status int, method, path string, v interface{}) ([]byte, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return d.expect(status, method, path, "", buf)
+}
+
+// poll calls check until it reports done, or fails after ceremonyTimeout.
+func poll(what string, check func() (bool, error)) error {
+	deadline := time.Now().Add(ceremonyTimeout)
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for %s", what)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// waitForParticipants polls a list of participants until it contains every expected one.
+func (d *device) waitForParticipants(path string, expected []string) error {
+	return poll(path, func() (bool, error) {
+		code, buf, err := d.do(http.MethodGet, path, "", nil)
+		if err != nil || code != http.StatusOK {
+			return false, err
+		}
+		var participants []string
+		if err := json.Unmarshal(buf, &participants); err != nil {
+			return false, err
+		}
+		return containsAll(participants, expected), nil
+	})
+}
+
+func containsAll(list, expected []string) bool {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+	for _, item := range expected {
+		if !set[item] {
+			return false
+		}
+	}
+	return true
+}
+
+func hashOf(body string) string {
+	h := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(h[:])
+}
+
+func roundBody(round int, from, to string) string {
+	return fmt.Sprintf("round %d from %s to %s", round, from, to)
+}
+
+// sendRound sends the message of a round to every other party.
+func (d *device) sendRound(sessionID, messageID string, round int, parties []string) error {
+	for _, to := range parties {
+		if to == d.id {
+			continue
+		}
+		body := roundBody(round, d.id, to)
+		m := model.Message{
+			SessionID:  sessionID,
+			From:       d.id,
+			To:         []string{to},
+			Body:       []byte(body),
+			Hash:       hashOf(body),
+			SequenceNo: uint64(round),
+		}
+		buf, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if _, err := d.expect(http.StatusAccepted, http.MethodPost, "/message/"+sessionID, messageID, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receiveRound polls the inbox until the message of the round arrived from every other party,
+// each message is deleted once it is processed, like the apps do.
+func (d *device) receiveRound(sessionID, messageID string, round int, parties []string) error {
+	pending := make(map[string]bool)
+	for _, from := range parties {
+		if from != d.id {
+			pending[from] = true
+		}
+	}
+	inbox := fmt.Sprintf("/message/%s/%s", sessionID, url.QueryEscape(d.id))
+	return poll(fmt.Sprintf("round %d at %s", round, d.id), func() (bool, error) {
+		buf, err := d.expect(http.StatusOK, http.MethodGet, inbox, messageID, nil)
+		if err != nil {
+			return false, err
+		}
+		var messages []model.Message
+		if err := json.Unmarshal(buf, &messages); err != nil {
+			return false, err
+		}
+		for _, m := range messages {
+			if int(m.SequenceNo) != round {
+				continue
+			}
+			if string(m.Body) != roundBody(round, m.From, d.id) || m.Hash != hashOf(string(m.Body)) {
+				return false, fmt.Errorf("%s received a corrupted message %+v", d.id, m)
+			}
+			if !pending[m.From] {
+				return false, fmt.Errorf("%s received round %d from %s twice", d.id, round, m.From)
+			}
+			delete(pending, m.From)
+			if _, err := d.expect(http.StatusOK, http.MethodDelete, inbox+"/"+m.Hash, messageID, nil); err != nil {
+				return false, err
+			}
+		}
+		return len(pending) == 0, nil
+	})
+}
+
+// runRounds exchanges the messages of every round with the other parties.
+func (d *device) runRounds(sessionID, messageID string, rounds int, parties []string) error {
+	for round := 1; round <= rounds; round++ {
+		if err := d.sendRound(sessionID, messageID, round, parties); err != nil {
+			return err
+		}
+		if err := d.receiveRound(sessionID, messageID, round, parties); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runDevices runs fn concurrently for every device and returns all the errors.
+func runDevices(devices []*device, fn func(d *device) error) error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(devices))
+	for _, d := range devices {
+		wg.Add(1)
+		go func(d *device) {
+			defer wg.Done()
+			if err := fn(d); err != nil {
+				errs <- fmt.Errorf("%s: %w", d.id, err)
+			}
+		}(d)
+	}
+	wg.Wait()
+	close(errs)
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	return errors.Join(all...)
+}
+
+// keygen runs a keygen ceremony among all the devices, the first one is the initiator.
+func keygen(sessionID string, devices []*device, rounds int) error {
+	parties := ids(devices)
+	leader := devices[0]
+	return runDevices(devices, func(d *device) error {
+		if _, err := d.expectJSON(http.StatusCreated, http.MethodPost, "/"+sessionID, []string{d.id}); err != nil {
+			return err
+		}
+		if d == leader {
+			if err := d.waitForParticipants("/"+sessionID, parties); err != nil {
+				return err
+			}
+			if _, err := d.expectJSON(http.StatusOK, http.MethodPost, "/start/"+sessionID, parties); err != nil {
+				return err
+			}
+		}
+		if err := d.waitForParticipants("/start/"+sessionID, parties); err != nil {
+			return err
+		}
+		if err := d.runRounds(sessionID, "", rounds, parties); err != nil {
+			return err
+		}
+		if _, err := d.expectJSON(http.StatusOK, http.MethodPost, "/complete/"+sessionID, []string{d.id}); err != nil {
+			return err
+		}
+		return d.waitForParticipants("/complete/"+sessionID, parties)
+	})
+}
+
+// keysign runs a keysign ceremony for every message among the signers, the first one is the initiator.
+// The initiator uploads the payload and the setup message, every signer fetches and verifies them.
+func keysign(sessionID string, signers []*device, messageIDs []string, rounds int) error {
+	parties := ids(signers)
+	leader := signers[0]
+	payload := fmt.Sprintf(`{"session":%q,"keysign":"payload"}`, sessionID)
+	payloadHash := hashOf(payload)
+	return runDevices(signers, func(d *device) error {
+		if d == leader {
+			if _, err := d.expect(http.StatusOK, http.MethodPost, "/payload/"+payloadHash, "", []byte(payload)); err != nil {
+				return err
+			}
+			for _, messageID := range messageIDs {
+				if _, err := d.expect(http.StatusCreated, http.MethodPost, "/setup-message/"+sessionID, messageID, []byte("setup "+messageID)); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := d.expectJSON(http.StatusCreated, http.MethodPost, "/"+sessionID, []string{d.id}); err != nil {
+			return err
+		}
+		if d == leader {
+			if err := d.waitForParticipants("/"+sessionID, parties); err != nil {
+				return err
+			}
+			if _, err := d.expectJSON(http.StatusOK, http.MethodPost, "/start/"+sessionID, parties); err != nil {
+				return err
+			}
+		}
+		if err := d.waitForParticipants("/start/"+sessionID, parties); err != nil {
+			return err
+		}
+		buf, err := d.expect(http.StatusOK, http.MethodGet, "/payload/"+payloadHash, "", nil)
+		if err != nil {
+			return err
+		}
+		if string(buf) != payload {
+			return fmt.Errorf("unexpected payload %q", buf)
+		}
+		for _, messageID := range messageIDs {
+			buf, err := d.expect(http.StatusOK, http.MethodGet, "/setup-message/"+sessionID, messageID, nil)
+			if err != nil {
+				return err
+			}
+			if string(buf) != "setup "+messageID {
+				return fmt.Errorf("unexpected setup message %q", buf)
+			}
+			if err := d.runRounds(sessionID, messageID, rounds, parties); err != nil {
+				return err
+			}
+			signature := "signature of " + messageID
+			if d == leader {
+				if _, err := d.expect(http.StatusOK, http.MethodPost, "/complete/"+sessionID+"/keysign", messageID, []byte(signature)); err != nil {
+					return err
+				}
+			}
+			err = poll("keysign finished", func() (bool, error) {
+				code, buf, err := d.do(http.MethodGet, "/complete/"+sessionID+"/keysign", messageID, nil)
+				if err != nil || code == http.StatusNotFound {
+					return false, err
+				}
+				if code != http.StatusOK || string(buf) != signature {
+					return false, fmt.Errorf("unexpected keysign result %d %q", code, buf)
+				}
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := d.expectJSON(http.StatusOK, http.MethodPost, "/complete/"+sessionID, []string{d.id}); err != nil {
+			return err
+		}
+		return d.waitForParticipants("/complete/"+sessionID, parties)
+	})
+}
+
+// endSession deletes the session and checks nothing of it is left in storage.
+func endSession(t *testing.T, r *relay, sessionID string, parties []string, messageIDs []string) {
+	t.Helper()
+	d := newDevices(r, parties[0])[0]
+	if _, err := d.expect(http.StatusOK, http.MethodDelete, "/"+sessionID, "", nil); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, key := range []string{storage.SessionKey(sessionID), storage.StartKey(sessionID), storage.CompleteKey(sessionID)} {
+		participants, err := r.store.GetSession(ctx, key)
+		if err != nil || len(participants) != 0 {
+			t.Errorf("%s left after the session ended: %v, err: %v", key, participants, err)
+		}
+	}
+	for _, messageID := range append([]string{""}, messageIDs...) {
+		for _, p := range parties {
+			key := storage.MessageKey(sessionID, p, messageID)
+			messages, err := r.store.GetMessages(ctx, key)
+			if err != nil || len(messages) != 0 {
+				t.Errorf("%s left after the session ended: %d messages, err: %v", key, len(messages), err)
+			}
+		}
+		for _, key := range []string{storage.SetupKey(sessionID, messageID), storage.KeysignCompleteKey(sessionID, messageID)} {
+			if _, err := r.store.GetValue(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("%s left after the session ended, err: %v", key, err)
+			}
+		}
+	}
+}
+
+func TestKeygenCeremony(t *testing.T) {
+	for _, n := range []int{2, 3, 5} {
+		t.Run(fmt.Sprintf("%d devices", n), func(t *testing.T) {
+			r := newRelay(t)
+			var names []string
+			for i := 0; i < n; i++ {
+				// device IDs contain spaces, they have to be query escaped in the inbox path
+				names = append(names, fmt.Sprintf("iPhone %d-%04x", i, i*7919))
+			}
+			devices := newDevices(r, names...)
+			sessionID := fmt.Sprintf("keygen-%d", n)
+			if err := keygen(sessionID, devices, 4); err != nil {
+				t.Fatal(err)
+			}
+			endSession(t, r, sessionID, names, nil)
+		})
+	}
+}
+
+func TestKeysignCeremony(t *testing.T) {
+	tests := []struct {
+		name       string
+		vault      int
+		signers    int
+		messageIDs []string
+	}{
+		{name: "2 of 2", vault: 2, signers: 2, messageIDs: []string{"msg-1"}},
+		{name: "2 of 3", vault: 3, signers: 2, messageIDs: []string{"msg-1", "msg-2"}},
+		{name: "3 of 5", vault: 5, signers: 3, messageIDs: []string{"msg-1", "msg-2", "msg-3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRelay(t)
+			var names []string
+			for i := 0; i < tt.vault; i++ {
+				names = append(names, fmt.Sprintf("device %d", i))
+			}
+			devices := newDevices(r, names...)
+			if err := keygen("keygen", devices, 3); err != nil {
+				t.Fatal(err)
+			}
+			endSession(t, r, "keygen", names, nil)
+
+			signers := devices[:tt.signers]
+			if err := keysign("keysign", signers, tt.messageIDs, 3); err != nil {
+				t.Fatal(err)
+			}
+			endSession(t, r, "keysign", ids(signers), tt.messageIDs)
+		})
+	}
+}
+
+// TestConcurrentCeremonies runs ceremonies of different sessions at the same time, they must not see each other.
+func TestConcurrentCeremonies(t *testing.T) {
+	r := newRelay(t)
+	const sessions = 4
+	var wg sync.WaitGroup
+	errs := make([]error, sessions)
+	for i := 0; i < sessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// the same device IDs are used by every session
+			devices := newDevices(r, "a", "b", "c")
+			sessionID := fmt.Sprintf("session-%d", i)
+			if i%2 == 0 {
+				errs[i] = keygen(sessionID, devices, 3)
+			} else {
+				errs[i] = keysign(sessionID, devices[:2], []string{"msg"}, 3)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < sessions; i++ {
+		participants, err := r.store.GetSession(context.Background(), storage.SessionKey(fmt.Sprintf("session-%d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(participants)
+		want := []string{"a", "b", "c"}
+		if i%2 == 1 {
+			want = want[:2]
+		}
+		if fmt.Sprint(participants) != fmt.Sprint(want) {
+			t.Errorf("session-%d: expected participants %v, got %v", i, want, participants)
+		}
+	}
+}
+
+// TestInboxIsolation checks a device only receives the messages addressed to it, and a message ID keeps
+// the messages of a keysign apart from the messages of the session.
+func TestInboxIsolation(t *testing.T) {
+	r := newRelay(t)
+	devices := newDevices(r, "a", "b", "c")
+	a, b, c := devices[0], devices[1], devices[2]
+	if err := a.sendRound("session", "", 1, []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.sendRound("session", "msg", 2, []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	inbox := func(d *device, messageID string) []model.Message {
+		t.Helper()
+		buf, err := d.expect(http.StatusOK, http.MethodGet, "/message/session/"+d.id, messageID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var messages []model.Message
+		if err := json.Unmarshal(buf, &messages); err != nil {
+			t.Fatal(err)
+		}
+		return messages
+	}
+	if messages := inbox(c, ""); len(messages) != 0 {
+		t.Fatalf("c received messages addressed to b: %+v", messages)
+	}
+	if messages := inbox(b, ""); len(messages) != 1 || messages[0].SequenceNo != 1 {
+		t.Fatalf("expected the session message only, got %+v", messages)
+	}
+	if messages := inbox(b, "msg"); len(messages) != 1 || messages[0].SequenceNo != 2 {
+		t.Fatalf("expected the keysign message only, got %+v", messages)
+	}
+	// posting the same message again doesn't deliver it twice
+	if err := a.sendRound("session", "", 1, []string{"b"}); err != nil {
+		t.Fatal(err)
+	}
+	if messages := inbox(b, ""); len(messages) != 1 {
+		t.Fatalf("expected the message to be delivered once, got %d", len(messages))
+	}
+	endSession(t, r, "session", ids(devices), []string{"msg"})
+}
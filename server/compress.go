@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/labstack/echo/v4"
+
+	"github.com/vultisig/vultisig-relay/metrics"
+)
+
+// content encodings of the compressed responses
+const (
+	encodingGzip = "gzip"
+	encodingZstd = "zstd"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	zstdWriters = sync.Pool{New: func() interface{} {
+		// the error is only returned for invalid options
+		w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// negotiateEncoding returns the content encoding the Accept-Encoding header prefers, zstd on a tie,
+// or an empty string when the client accepts neither zstd nor gzip.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, item := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encodingGzip && name != encodingZstd {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ || (q == bestQ && q > 0 && name == encodingZstd) {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressResponses compresses the responses of the clients that accept zstd or gzip, from minSize bytes.
+// Smaller responses are sent as is, they are buffered until they reach minSize or the handler returns.
+func compressResponses(minSize int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			res := c.Response()
+			res.Header().Add(echo.HeaderVary, echo.HeaderAcceptEncoding)
+			encoding := negotiateEncoding(c.Request().Header.Get(echo.HeaderAcceptEncoding))
+			if encoding == "" || c.Request().Method == http.MethodHead {
+				return next(c)
+			}
+			w := &compressWriter{ResponseWriter: res.Writer, encoding: encoding, minSize: minSize}
+			res.Writer = w
+			defer func() {
+				w.finish()
+				// the error handler writes to the original writer when the handler failed without writing
+				res.Writer = w.ResponseWriter
+			}()
+			return next(c)
+		}
+	}
+}
+
+// compressWriter buffers the response until it reaches minSize, then compresses it.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	// passThrough is set when the response is already encoded by the handler
+	passThrough bool
+	encoder     io.WriteCloser
+	// in and out are the bytes before and after compression
+	in  int
+	out countingWriter
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w http.ResponseWriter
+	n int
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += n
+	return n, err
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	// the header is written once it is known whether the response is compressed
+	w.code = code
+	w.wroteHeader = true
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	switch {
+	case w.passThrough:
+		return w.ResponseWriter.Write(b)
+	case w.encoder != nil:
+		w.in += len(b)
+		return w.encoder.Write(b)
+	}
+	n, _ := w.buf.Write(b)
+	if w.buf.Len() >= w.minSize {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// start writes the header and the buffered body, compressed unless the handler already set a content encoding
+// or answers a range, which is a range of the uncompressed content.
+func (w *compressWriter) start() error {
+	header := w.Header()
+	if header.Get(echo.HeaderContentEncoding) != "" || w.code == http.StatusPartialContent {
+		w.passThrough = true
+	} else {
+		header.Set(echo.HeaderContentEncoding, w.encoding)
+		header.Del(echo.HeaderContentLength)
+		w.out.w = w.ResponseWriter
+		if w.encoding == encodingZstd {
+			zw := zstdWriters.Get().(*zstd.Encoder)
+			zw.Reset(&w.out)
+			w.encoder = zw
+		} else {
+			gw := gzipWriters.Get().(*gzip.Writer)
+			gw.Reset(&w.out)
+			w.encoder = gw
+		}
+	}
+	w.writeHeader()
+	body := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	_, err := w.Write(body)
+	return err
+}
+
+func (w *compressWriter) writeHeader() {
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+}
+
+// finish writes what is left of the response, and records the bytes saved by compression.
+func (w *compressWriter) finish() {
+	if w.encoder == nil {
+		if !w.passThrough && (w.wroteHeader || w.buf.Len() > 0) {
+			w.writeHeader()
+			_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		}
+		return
+	}
+	_ = w.encoder.Close()
+	switch e := w.encoder.(type) {
+	case *zstd.Encoder:
+		e.Reset(io.Discard)
+		zstdWriters.Put(e)
+	case *gzip.Writer:
+		e.Reset(io.Discard)
+		gzipWriters.Put(e)
+	}
+	metrics.CompressedResponses.WithLabelValues(w.encoding).Inc()
+	if saved := w.in - w.out.n; saved > 0 {
+		metrics.ResponseCompressionSavedBytes.WithLabelValues(w.encoding).Add(float64(saved))
+	}
+}
+
+// Flush sends what is buffered, compressed, to the client.
+func (w *compressWriter) Flush() {
+	if w.encoder == nil && !w.passThrough {
+		_ = w.start()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the original writer, for http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// decodeContent decompresses a response body with the given content encoding.
+func decodeContent(encoding string, body []byte) ([]byte, error) {
+	switch encoding {
+	case "":
+		return body, nil
+	case encodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	case encodingZstd:
+		r, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+	return nil, fmt.Errorf("unknown content encoding %s", encoding)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: ""},
+		{acceptEncoding: "br, deflate", want: ""},
+		{acceptEncoding: "gzip, deflate, br", want: encodingGzip},
+		{acceptEncoding: "gzip, zstd", want: encodingZstd},
+		{acceptEncoding: "zstd;q=0.5, gzip", want: encodingGzip},
+		{acceptEncoding: "gzip;q=0", want: ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.acceptEncoding); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, expected %q", tt.acceptEncoding, got, tt.want)
+		}
+	}
+}
+
+func TestCompressResponses(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	setup := strings.Repeat("setup message ", 1000)
+	req := httptest.NewRequest(http.MethodPost, "/setup-message/s1", strings.NewReader(setup))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+	}{
+		{name: "identity", path: "/setup-message/s1", wantBody: setup},
+		{name: "gzip", path: "/setup-message/s1", acceptEncoding: "gzip", wantEncoding: encodingGzip, wantBody: setup},
+		{name: "zstd", path: "/setup-message/s1", acceptEncoding: "gzip, zstd", wantEncoding: encodingZstd, wantBody: setup},
+		{name: "below min size", path: "/ping", acceptEncoding: "zstd", wantBody: "Voltix Router is running"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			encoding := rec.Header().Get("Content-Encoding")
+			if rec.Code != http.StatusOK || encoding != tt.wantEncoding {
+				t.Fatalf("expected 200 with content encoding %q, got %d %q", tt.wantEncoding, rec.Code, encoding)
+			}
+			body, err := decodeContent(encoding, rec.Body.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Fatalf("expected %d bytes, got %d bytes", len(tt.wantBody), len(body))
+			}
+			if encoding != "" && rec.Body.Len() >= len(body) {
+				t.Fatalf("expected a compressed body, got %d bytes for %d bytes", rec.Body.Len(), len(body))
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	s.MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `relay_http_response_compression_saved_bytes_total{encoding="zstd"}`) {
+		t.Fatalf("expected the saved bytes in the metrics, got %s", rec.Body)
+	}
+}
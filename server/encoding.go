@@ -0,0 +1,125 @@
+package server
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/relaypb"
+)
+
+// Media types of the message routes besides JSON, the default. They carry the body of a message as bytes,
+// without the base64 and JSON escaping of the JSON encoding.
+const (
+	MIMEApplicationCBOR     = "application/cbor"
+	MIMEApplicationProtobuf = "application/x-protobuf"
+)
+
+// mediaType returns the media type of a Content-Type header, without its parameters.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// bindMessage decodes the message of the request, in the encoding of its Content-Type.
+func bindMessage(c echo.Context, m *model.Message) error {
+	contentType := mediaType(c.Request().Header.Get(echo.HeaderContentType))
+	switch contentType {
+	case MIMEApplicationCBOR, MIMEApplicationProtobuf:
+	default:
+		if err := c.Bind(m); err != nil {
+			return badRequest(CodeInvalidBody, "message must be a JSON object", err)
+		}
+		return nil
+	}
+	buf, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return badRequest(CodeInvalidBody, "fail to read body", err)
+	}
+	if contentType == MIMEApplicationCBOR {
+		if err := cbor.Unmarshal(buf, m); err != nil {
+			return badRequest(CodeInvalidBody, "message must be a CBOR map", err)
+		}
+		return nil
+	}
+	var pb relaypb.Message
+	if err := proto.Unmarshal(buf, &pb); err != nil {
+		return badRequest(CodeInvalidBody, "message must be a vultisig.relay.v1.Message", err)
+	}
+	*m = fromProtoMessage(&pb)
+	return nil
+}
+
+// negotiate returns the media type of the response, the supported type the Accept header prefers.
+// JSON is answered when the client accepts anything, or nothing the relay supports.
+func negotiate(accept string) string {
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+	var candidates []candidate
+	for _, item := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{mediaType: mt, q: q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		switch c.mediaType {
+		case MIMEApplicationCBOR, MIMEApplicationProtobuf:
+			return c.mediaType
+		case echo.MIMEApplicationJSON, "application/*", "*/*":
+			return echo.MIMEApplicationJSON
+		}
+	}
+	return echo.MIMEApplicationJSON
+}
+
+// writeMessages answers the messages in the encoding the client accepts. Vary is only set on v2 and on binary
+// responses, v1 JSON responses keep the headers old apps and caches saw before encodings were negotiated.
+func writeMessages(c echo.Context, messages []model.Message) error {
+	encoding := negotiate(c.Request().Header.Get(echo.HeaderAccept))
+	if isV2(c) || encoding != echo.MIMEApplicationJSON {
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	}
+	switch encoding {
+	case MIMEApplicationCBOR:
+		buf, err := cbor.Marshal(messages)
+		if err != nil {
+			return &apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "fail to encode messages", err: err}
+		}
+		return c.Blob(http.StatusOK, MIMEApplicationCBOR, buf)
+	case MIMEApplicationProtobuf:
+		list := &relaypb.MessageList{Messages: make([]*relaypb.Message, 0, len(messages))}
+		for _, m := range messages {
+			list.Messages = append(list.Messages, toProtoMessage(m))
+		}
+		buf, err := proto.Marshal(list)
+		if err != nil {
+			return &apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "fail to encode messages", err: err}
+		}
+		return c.Blob(http.StatusOK, MIMEApplicationProtobuf, buf)
+	}
+	return c.JSON(http.StatusOK, messages)
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/relaypb"
+)
+
+func encodeMessage(t *testing.T, contentType string, m model.Message) []byte {
+	t.Helper()
+	var buf []byte
+	var err error
+	switch contentType {
+	case MIMEApplicationCBOR:
+		buf, err = cbor.Marshal(m)
+	case MIMEApplicationProtobuf:
+		buf, err = proto.Marshal(toProtoMessage(m))
+	default:
+		buf, err = json.Marshal(m)
+	}
+	if err != nil {
+		t.Fatalf("fail to encode message, err: %v", err)
+	}
+	return buf
+}
+
+func decodeMessages(t *testing.T, contentType string, buf []byte) []model.Message {
+	t.Helper()
+	var messages []model.Message
+	var err error
+	switch contentType {
+	case MIMEApplicationCBOR:
+		err = cbor.Unmarshal(buf, &messages)
+	case MIMEApplicationProtobuf:
+		var list relaypb.MessageList
+		err = proto.Unmarshal(buf, &list)
+		for _, m := range list.GetMessages() {
+			messages = append(messages, fromProtoMessage(m))
+		}
+	default:
+		err = json.Unmarshal(buf, &messages)
+	}
+	if err != nil {
+		t.Fatalf("fail to decode messages %q, err: %v", buf, err)
+	}
+	return messages
+}
+
+// TestMessageEncodings sends a binary message in every encoding and receives it in every encoding.
+func TestMessageEncodings(t *testing.T) {
+	encodings := []string{"application/json", MIMEApplicationCBOR, MIMEApplicationProtobuf}
+	want := model.Message{SessionID: "s1", From: "a", To: []string{"b"}, Body: []byte{0x00, 0xff, 'r', '1'}, Hash: "h1", SequenceNo: 1}
+	for _, sent := range encodings {
+		for _, accepted := range encodings {
+			t.Run(sent+" to "+accepted, func(t *testing.T) {
+				_, handler := newTestServer(t, nil)
+				req := httptest.NewRequest(http.MethodPost, v2Prefix+"/message/s1", bytes.NewReader(encodeMessage(t, sent, want)))
+				req.Header.Set("Content-Type", sent)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if rec.Code != http.StatusAccepted {
+					t.Fatalf("fail to post message, status: %d %s", rec.Code, rec.Body)
+				}
+
+				req = httptest.NewRequest(http.MethodGet, v2Prefix+"/message/s1/b", nil)
+				req.Header.Set("Accept", accepted)
+				rec = httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				if got := rec.Header().Get("Content-Type"); mediaType(got) != accepted {
+					t.Fatalf("expected content type %s, got %s", accepted, got)
+				}
+				messages := decodeMessages(t, accepted, rec.Body.Bytes())
+				if len(messages) != 1 || !reflect.DeepEqual(messages[0], want) {
+					t.Fatalf("expected %+v, got %+v", want, messages)
+				}
+			})
+		}
+	}
+}
+
+func TestMessageVary(t *testing.T) {
+	tests := []struct {
+		path     string
+		accept   string
+		wantVary bool
+	}{
+		{path: "/message/s1/b", wantVary: false},
+		{path: "/message/s1/b", accept: "application/json", wantVary: false},
+		{path: "/message/s1/b", accept: "*/*", wantVary: false},
+		{path: "/message/s1/b", accept: MIMEApplicationCBOR, wantVary: true},
+		{path: "/message/s1/b", accept: MIMEApplicationProtobuf, wantVary: true},
+		{path: v2Prefix + "/message/s1/b", wantVary: true},
+		{path: v2Prefix + "/message/s1/b", accept: MIMEApplicationCBOR, wantVary: true},
+	}
+	_, handler := newTestServer(t, nil)
+	if rec := serveHTTP(handler, http.MethodPost, "/message/s1", `{"session_id":"s1","from":"a","to":["b"],"body":"cjE=","hash":"h1","sequence_no":1}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("fail to post message, status: %d", rec.Code)
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: unexpected status %d", tt.path, rec.Code)
+		}
+		vary := rec.Header().Values(echo.HeaderVary)
+		if slices.Contains(vary, echo.HeaderAccept) != tt.wantVary {
+			t.Errorf("GET %s with Accept %q: expected Vary: Accept %v, got %q", tt.path, tt.accept, tt.wantVary, vary)
+		}
+	}
+}
+
+func TestMessageJSONCompatibility(t *testing.T) {
+	text := `{"session_id":"s1","from":"a","to":["b"],"body":"cm91bmQgMQ==","hash":"h1","sequence_no":1}`
+	var m model.Message
+	if err := json.Unmarshal([]byte(text), &m); err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Body) != "cm91bmQgMQ==" {
+		t.Fatalf("expected the body string as is, got %q", m.Body)
+	}
+	buf, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != text {
+		t.Fatalf("expected %s, got %s", text, buf)
+	}
+	m.Body = []byte{0xff}
+	if buf, _ = json.Marshal(m); !bytes.Contains(buf, []byte(`"body_base64":"/w=="`)) {
+		t.Fatalf("expected a binary body in body_base64, got %s", buf)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: "application/json"},
+		{accept: "*/*", want: "application/json"},
+		{accept: "application/cbor", want: MIMEApplicationCBOR},
+		{accept: "application/json, application/x-protobuf", want: "application/json"},
+		{accept: "application/json;q=0.5, application/x-protobuf", want: MIMEApplicationProtobuf},
+		{accept: "application/cbor;q=0, */*", want: "application/json"},
+		{accept: "text/html", want: "application/json"},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.accept); got != tt.want {
+			t.Errorf("negotiate(%q) = %s, expected %s", tt.accept, got, tt.want)
+		}
+	}
+}
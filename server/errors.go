@@ -0,0 +1,169 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Error codes of the v2 API, clients branch on the code, the message is for humans.
+const (
+	CodeInvalidSessionID     = "invalid_session_id"
+	CodeInvalidParticipantID = "invalid_participant_id"
+	CodeInvalidHash          = "invalid_hash"
+	CodeInvalidBody          = "invalid_body"
+	CodeHashMismatch         = "hash_mismatch"
+	CodeInvalidRange         = "invalid_range"
+	CodeOffsetMismatch       = "offset_mismatch"
+	CodeUploadIncomplete     = "upload_incomplete"
+	CodeSessionNotFound      = "session_not_found"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeRequestTooLarge      = "request_too_large"
+	CodeRequestCancelled     = "request_cancelled"
+	CodeDraining             = "draining"
+	CodeMaintenance          = "maintenance"
+	CodeReadOnly             = "read_only"
+	CodeStorageUnavailable   = "storage_unavailable"
+	CodeCorruptValue         = "corrupt_value"
+	CodeInternal             = "internal"
+)
+
+// ErrorResponse is the body of the failed responses of the v2 API.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Retryable tells whether the same request may succeed later
+	Retryable bool   `json:"retryable"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// apiError is returned by the relay handlers, the middleware of the API version writes it.
+type apiError struct {
+	status    int
+	code      string
+	message   string
+	retryable bool
+	// v1Status is the status the routes without version answer with, when it differs from status
+	v1Status int
+	// err is the cause, it is logged but never sent to the client
+	err error
+}
+
+func (e *apiError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("%s: %s, err: %s", e.code, e.message, e.err)
+	}
+	return e.code + ": " + e.message
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(code, message string, err error) *apiError {
+	return &apiError{status: http.StatusBadRequest, code: code, message: message, err: err}
+}
+
+func notFound(code, message string) *apiError {
+	return &apiError{status: http.StatusNotFound, code: code, message: message}
+}
+
+func unavailable(code, message string) *apiError {
+	return &apiError{status: http.StatusServiceUnavailable, code: code, message: message, retryable: true}
+}
+
+func cancelled(err error) *apiError {
+	return &apiError{status: http.StatusRequestTimeout, code: CodeRequestCancelled, message: "request cancelled", retryable: true, err: err}
+}
+
+func storageError(message string, err error) *apiError {
+	return &apiError{status: http.StatusInternalServerError, code: CodeStorageUnavailable, message: message, retryable: true, err: err}
+}
+
+// apiVersionKey is set in the context of the v2 routes.
+const apiVersionKey = "api_version"
+
+// isV2 returns true when the request is served by a v2 route.
+func isV2(c echo.Context) bool {
+	return c.Get(apiVersionKey) == 2
+}
+
+// v1Errors writes the errors of the routes without version as a bare status code, old apps rely on it.
+func v1Errors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		err := next(c)
+		var ae *apiError
+		if !errors.As(err, &ae) {
+			return err
+		}
+		logAPIError(c, ae)
+		if c.Response().Committed {
+			return nil
+		}
+		status := ae.status
+		if ae.v1Status != 0 {
+			status = ae.v1Status
+		}
+		return c.NoContent(status)
+	}
+}
+
+// v2Errors writes the errors of the v2 routes as an ErrorResponse.
+func v2Errors(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Set(apiVersionKey, 2)
+		err := next(c)
+		if err == nil {
+			return nil
+		}
+		var ae *apiError
+		if !errors.As(err, &ae) {
+			ae = fromHTTPError(err)
+		}
+		logAPIError(c, ae)
+		if c.Response().Committed {
+			return nil
+		}
+		return c.JSON(ae.status, ErrorResponse{
+			Code:      ae.code,
+			Message:   ae.message,
+			Retryable: ae.retryable,
+			RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+		})
+	}
+}
+
+// fromHTTPError converts the errors of echo, such as unknown routes, and unexpected errors.
+func fromHTTPError(err error) *apiError {
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		return &apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "internal error", err: err}
+	}
+	ae := &apiError{status: he.Code, message: http.StatusText(he.Code), err: he.Internal}
+	switch he.Code {
+	case http.StatusNotFound:
+		ae.code = CodeNotFound
+	case http.StatusMethodNotAllowed:
+		ae.code = CodeMethodNotAllowed
+	case http.StatusRequestEntityTooLarge:
+		ae.code = CodeRequestTooLarge
+	case http.StatusBadRequest:
+		ae.code = CodeInvalidBody
+	default:
+		ae.code = CodeInternal
+		ae.retryable = he.Code >= http.StatusInternalServerError
+	}
+	return ae
+}
+
+func logAPIError(c echo.Context, ae *apiError) {
+	switch {
+	case ae.status >= http.StatusInternalServerError:
+		c.Logger().Error(ae)
+	case ae.err != nil:
+		c.Logger().Debug(ae)
+	}
+}
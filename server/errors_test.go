@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// failingStorage fails every read with err.
+type failingStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s failingStorage) GetSession(ctx context.Context, key string) ([]string, error) {
+	return nil, s.err
+}
+
+func (s failingStorage) GetValue(ctx context.Context, key string) (string, error) {
+	return "", s.err
+}
+
+func (s failingStorage) GetMessages(ctx context.Context, key string) ([]model.Message, error) {
+	return nil, s.err
+}
+
+func newTestServer(t *testing.T, readErr error) (*Server, http.Handler) {
+	t.Helper()
+	var store storage.Storage
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatalf("fail to create storage, err: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if readErr != nil {
+		store = failingStorage{Storage: store, err: readErr}
+	}
+	cfg := config.DefaultConfig()
+	s := NewServer(&cfg, store)
+	handler := s.Handler()
+	s.e.Logger.SetOutput(io.Discard)
+	return s, validating(t, handler)
+}
+
+func TestErrorResponses(t *testing.T) {
+	storageDown := errors.New("connection refused")
+	tests := []struct {
+		name          string
+		readErr       error
+		method        string
+		path          string
+		body          string
+		wantV1Status  int
+		wantV1Body    string
+		wantStatus    int
+		wantCode      string
+		wantRetryable bool
+	}{
+		{name: "participant not escaped", method: http.MethodGet, path: "/message/s1/%25zz", wantV1Status: http.StatusBadRequest, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidParticipantID},
+		{name: "invalid participants", method: http.MethodPost, path: "/s1", body: `{"a":1}`, wantV1Status: http.StatusBadRequest, wantStatus: http.StatusBadRequest, wantCode: CodeInvalidBody},
+		{name: "hash mismatch", method: http.MethodPost, path: "/payload/abc", body: "x", wantV1Status: http.StatusBadRequest, wantStatus: http.StatusBadRequest, wantCode: CodeHashMismatch},
+		{name: "session expired", readErr: storage.ErrNotFound, method: http.MethodGet, path: "/s1", wantV1Status: http.StatusNotFound, wantStatus: http.StatusNotFound, wantCode: CodeSessionNotFound},
+		{name: "storage down", readErr: storageDown, method: http.MethodGet, path: "/s1", wantV1Status: http.StatusNotFound, wantStatus: http.StatusInternalServerError, wantCode: CodeStorageUnavailable, wantRetryable: true},
+		{name: "storage down while polling", readErr: storageDown, method: http.MethodGet, path: "/message/s1/a", wantV1Status: http.StatusOK, wantV1Body: "[]\n", wantStatus: http.StatusInternalServerError, wantCode: CodeStorageUnavailable, wantRetryable: true},
+		{name: "unknown route", method: http.MethodPatch, path: "/s1", wantV1Status: http.StatusNotFound, wantV1Body: "{\"message\":\"Not Found\"}\n", wantStatus: http.StatusNotFound, wantCode: CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, handler := newTestServer(t, tt.readErr)
+			serve := func(path string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(tt.method, path, strings.NewReader(tt.body))
+				req.Header.Set("Content-Type", "application/json")
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			rec := serve(tt.path)
+			if rec.Code != tt.wantV1Status || rec.Body.String() != tt.wantV1Body {
+				t.Fatalf("expected v1 to answer %d %q, got %d %q", tt.wantV1Status, tt.wantV1Body, rec.Code, rec.Body)
+			}
+
+			rec = serve(v2Prefix + tt.path)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected v2 status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			var resp ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("fail to decode error response %q, err: %v", rec.Body, err)
+			}
+			if resp.Code != tt.wantCode || resp.Retryable != tt.wantRetryable || resp.Message == "" {
+				t.Fatalf("unexpected error response %+v", resp)
+			}
+			if resp.RequestID == "" || resp.RequestID != rec.Header().Get("X-Request-Id") {
+				t.Fatalf("expected the request id of the response, got %q", resp.RequestID)
+			}
+			if strings.Contains(rec.Body.String(), storageDown.Error()) {
+				t.Fatal("storage errors must not leak to clients")
+			}
+		})
+	}
+}
+
+func TestV2Toggles(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	s.readOnly.Store(true)
+	req := httptest.NewRequest(http.MethodPost, v2Prefix+"/s1", strings.NewReader(`["a"]`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var resp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := ErrorResponse{Code: CodeReadOnly, Message: "relay is read-only", Retryable: true, RequestID: "req-1"}
+	if rec.Code != http.StatusServiceUnavailable || resp != want {
+		t.Fatalf("expected %+v, got %d %+v", want, rec.Code, resp)
+	}
+}
@@ -0,0 +1,566 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/relaypb"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// exchangePollInterval is how often an Exchange stream checks the inbox of its participant,
+// storage doesn't notify new messages.
+const exchangePollInterval = time.Millisecond * 100
+
+// grpcService serves the gRPC API on the storage of the relay, with the same toggles as the HTTP API.
+type grpcService struct {
+	relaypb.UnimplementedRelayServiceServer
+	s *Server
+}
+
+// RegisterGRPC registers the gRPC API of the relay on g, to serve it without StartGRPCServer, e.g. from tests.
+func (s *Server) RegisterGRPC(g grpc.ServiceRegistrar) {
+	relaypb.RegisterRelayServiceServer(g, &grpcService{s: s})
+}
+
+// grpcServerOptions counts the gRPC calls in flight, so draining waits for them like for HTTP requests.
+func (s *Server) grpcServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			defer s.track()()
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			defer s.track()()
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// StartGRPCServer serves the gRPC API on the gRPC port, with the TLS configuration of the relay.
+func (s *Server) StartGRPCServer() error {
+	opts := s.grpcServerOptions()
+	if s.tls.Enabled() {
+		reloader, err := newCertReloader(s.tls)
+		if err != nil {
+			return fmt.Errorf("fail to load tls config, err: %w", err)
+		}
+		go reloader.watch(s.ctx, s.e.Logger)
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.TLSConfig())))
+	}
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.grpc.Port))
+	if err != nil {
+		return fmt.Errorf("fail to listen on grpc port, err: %w", err)
+	}
+	g := grpc.NewServer(opts...)
+	s.RegisterGRPC(g)
+	s.grpcLock.Lock()
+	if s.ctx.Err() != nil {
+		// the relay shut down while the listener was opened
+		s.grpcLock.Unlock()
+		return lis.Close()
+	}
+	s.grpcServer = g
+	s.grpcLock.Unlock()
+	return g.Serve(lis)
+}
+
+// stopGRPCServer waits for the gRPC calls to finish, and cancels them once ctx is done.
+func (s *Server) stopGRPCServer(ctx context.Context) {
+	s.grpcLock.Lock()
+	g := s.grpcServer
+	s.grpcLock.Unlock()
+	if g == nil {
+		return
+	}
+	stopped := make(chan struct{})
+	go func() {
+		g.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		g.Stop()
+	}
+}
+
+// grpcError converts the error of a storage call to a status, storage errors are logged and not sent to clients.
+func (g *grpcService) grpcError(message string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, message)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	g.s.e.Logger.Errorf("%s, err: %s", message, err)
+	return status.Error(codes.Unavailable, message)
+}
+
+// checkToggles refuses the calls while the relay is in maintenance, and the writes while it is read-only.
+func (g *grpcService) checkToggles(write bool) error {
+	if g.s.maintenance.Load() {
+		return status.Error(codes.Unavailable, "relay is in maintenance")
+	}
+	if write && g.s.readOnly.Load() {
+		return status.Error(codes.Unavailable, "relay is read-only")
+	}
+	return nil
+}
+
+func requireSessionID(sessionID string) (string, error) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		return "", status.Error(codes.InvalidArgument, "session ID is empty")
+	}
+	return sessionID, nil
+}
+
+func (g *grpcService) JoinSession(ctx context.Context, req *relaypb.JoinSessionRequest) (*relaypb.JoinSessionResponse, error) {
+	if err := g.checkToggles(true); err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSession().GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	key := storage.SessionKey(sessionID)
+	if g.s.draining.Load() {
+		// while draining, participants can still join existing sessions, but no new session is created
+		existing, err := g.s.s.GetSession(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, g.grpcError("fail to get session", err)
+		}
+		if len(existing) == 0 {
+			return nil, status.Error(codes.Unavailable, "relay is shutting down, new sessions are refused")
+		}
+	}
+	if err := g.s.s.SetSession(ctx, key, req.GetSession().GetParticipants()); err != nil {
+		return nil, g.grpcError("fail to set session", err)
+	}
+	return &relaypb.JoinSessionResponse{}, nil
+}
+
+func (g *grpcService) GetSession(ctx context.Context, req *relaypb.GetSessionRequest) (*relaypb.GetSessionResponse, error) {
+	if err := g.checkToggles(false); err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	participants, err := g.s.s.GetSession(ctx, storage.SessionKey(sessionID))
+	if err != nil {
+		return nil, g.grpcError("session not found", err)
+	}
+	return &relaypb.GetSessionResponse{Session: &relaypb.Session{SessionId: sessionID, Participants: participants}}, nil
+}
+
+func (g *grpcService) EndSession(ctx context.Context, req *relaypb.EndSessionRequest) (*relaypb.EndSessionResponse, error) {
+	if err := g.checkToggles(true); err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.ReleaseSessionPayloads(ctx, g.s.s, sessionID); err != nil {
+		return nil, g.grpcError("fail to release payloads of session", err)
+	}
+	if err := g.s.s.DeleteSession(ctx, sessionID); err != nil {
+		return nil, g.grpcError("fail to delete session", err)
+	}
+	return &relaypb.EndSessionResponse{}, nil
+}
+
+// markerKey returns the storage key function of a marker.
+func markerKey(marker relaypb.Marker) (func(sessionID string) string, error) {
+	switch marker {
+	case relaypb.Marker_MARKER_START:
+		return storage.StartKey, nil
+	case relaypb.Marker_MARKER_COMPLETE:
+		return storage.CompleteKey, nil
+	}
+	return nil, status.Errorf(codes.InvalidArgument, "unknown marker %s", marker)
+}
+
+func (g *grpcService) SetMarker(ctx context.Context, req *relaypb.SetMarkerRequest) (*relaypb.SetMarkerResponse, error) {
+	if err := g.checkToggles(true); err != nil {
+		return nil, err
+	}
+	keyFunc, err := markerKey(req.GetMarker())
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSession().GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	key := keyFunc(sessionID)
+	if err := g.s.s.SetSession(ctx, key, req.GetSession().GetParticipants()); err != nil {
+		return nil, g.grpcError("fail to set participants", err)
+	}
+	if err := g.s.s.AddSessionKey(ctx, sessionID, key); err != nil {
+		return nil, g.grpcError("fail to track participants", err)
+	}
+	return &relaypb.SetMarkerResponse{}, nil
+}
+
+func (g *grpcService) GetMarker(ctx context.Context, req *relaypb.GetMarkerRequest) (*relaypb.GetMarkerResponse, error) {
+	if err := g.checkToggles(false); err != nil {
+		return nil, err
+	}
+	keyFunc, err := markerKey(req.GetMarker())
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	participants, err := g.s.s.GetSession(ctx, keyFunc(sessionID))
+	if err != nil {
+		return nil, g.grpcError("participants not found", err)
+	}
+	return &relaypb.GetMarkerResponse{Session: &relaypb.Session{SessionId: sessionID, Participants: participants}}, nil
+}
+
+func (g *grpcService) SetKeysignResult(ctx context.Context, req *relaypb.SetKeysignResultRequest) (*relaypb.SetKeysignResultResponse, error) {
+	if err := g.checkToggles(true); err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	key := storage.KeysignCompleteKey(sessionID, req.GetMessageId())
+	if err := g.s.s.SetValue(ctx, key, string(req.GetResult())); err != nil {
+		return nil, g.grpcError("fail to store keysign result", err)
+	}
+	if err := g.s.s.AddSessionKey(ctx, sessionID, key); err != nil {
+		return nil, g.grpcError("fail to track keysign result", err)
+	}
+	return &relaypb.SetKeysignResultResponse{}, nil
+}
+
+func (g *grpcService) GetKeysignResult(ctx context.Context, req *relaypb.GetKeysignResultRequest) (*relaypb.GetKeysignResultResponse, error) {
+	if err := g.checkToggles(false); err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	value, err := g.s.s.GetValue(ctx, storage.KeysignCompleteKey(sessionID, req.GetMessageId()))
+	if err != nil {
+		return nil, g.grpcError("keysign result not found", err)
+	}
+	return &relaypb.GetKeysignResultResponse{Result: []byte(value)}, nil
+}
+
+// UploadPayload stores the chunks of the stream as they arrive, they are hashed while they are written,
+// so the payload is never held whole in memory.
+func (g *grpcService) UploadPayload(stream relaypb.RelayService_UploadPayloadServer) error {
+	ctx := stream.Context()
+	if err := g.checkToggles(true); err != nil {
+		return err
+	}
+	req, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return status.Error(codes.InvalidArgument, "the stream has no payload")
+	}
+	if err != nil {
+		return err
+	}
+	payloadID, err := storage.ParsePayloadID(req.GetPayload().GetHash())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid payload ID, err: %s", err)
+	}
+	sessions := payloadSessions(req.GetSessionIds())
+	id, err := storage.NewPayloadID()
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	canonical, h := sha256.New(), payloadID.NewHash()
+	w := storage.NewPayloadWriter(ctx, g.s.s, storage.PayloadManifest{ID: id})
+	stored := false
+	defer func() {
+		if !stored {
+			// the chunks of a rejected payload are deleted, they expire when they can't be
+			_ = w.Discard()
+		}
+	}()
+	out := io.MultiWriter(w, canonical, h)
+	var size int64
+	for {
+		data := req.GetPayload().GetData()
+		size += int64(len(data))
+		if size > g.s.payload.MaxSize {
+			return status.Errorf(codes.InvalidArgument, "payload exceeds %d bytes", g.s.payload.MaxSize)
+		}
+		if _, err := out.Write(data); err != nil {
+			return g.grpcError("fail to store payload", err)
+		}
+		req, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if result := hex.EncodeToString(h.Sum(nil)); result != payloadID.Digest {
+		return status.Errorf(codes.InvalidArgument, "hash does not match, expected %s, got %s", payloadID.Digest, result)
+	}
+	if err := w.Flush(); err != nil {
+		return g.grpcError("fail to store payload", err)
+	}
+	// once the payload is stored its chunks may be shared, they are left to expire when storing fails
+	stored = true
+	if err := storage.StorePayload(ctx, g.s.s, payloadID, hex.EncodeToString(canonical.Sum(nil)), w.Manifest(), sessions); err != nil {
+		return g.grpcError("fail to store payload", err)
+	}
+	return stream.SendAndClose(&relaypb.UploadPayloadResponse{})
+}
+
+// GetPayload streams a payload in messages of one stored chunk, each chunk is verified before it is sent.
+func (g *grpcService) GetPayload(req *relaypb.GetPayloadRequest, stream relaypb.RelayService_GetPayloadServer) error {
+	ctx := stream.Context()
+	if err := g.checkToggles(false); err != nil {
+		return err
+	}
+	payloadID, err := storage.ParsePayloadID(req.GetHash())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid payload ID, err: %s", err)
+	}
+	if err := checkPayloadScope(ctx, g.s.s, payloadID, strings.TrimSpace(req.GetSessionId())); err != nil {
+		return g.grpcError("payload not found", err)
+	}
+	payload, err := storage.GetPayload(ctx, g.s.s, payloadID)
+	if err != nil {
+		return g.payloadError(err)
+	}
+	r, err := payload.NewReader(ctx, 0, payload.Size())
+	if err != nil {
+		return g.payloadError(err)
+	}
+	// the hash is only sent with the first chunk, an empty payload is one message without data
+	hash := payloadID.String()
+	for {
+		// a message may still be in use after Send returns, every chunk gets its own buffer
+		buf := make([]byte, storage.PayloadChunkSize)
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || hash != "" {
+			if sendErr := stream.Send(&relaypb.GetPayloadResponse{Payload: &relaypb.Payload{Hash: hash, Data: buf[:n]}}); sendErr != nil {
+				return sendErr
+			}
+			hash = ""
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil
+		}
+		if err != nil {
+			return g.payloadError(err)
+		}
+	}
+}
+
+// payloadError returns the status of a payload that can't be read.
+func (g *grpcService) payloadError(err error) error {
+	if errors.Is(err, storage.ErrCorruptPayload) {
+		return status.Error(codes.DataLoss, "stored payload does not match its hash")
+	}
+	return g.grpcError("payload not found", err)
+}
+
+func (g *grpcService) UploadSetupMessage(ctx context.Context, req *relaypb.UploadSetupMessageRequest) (*relaypb.UploadSetupMessageResponse, error) {
+	if err := g.checkToggles(true); err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	key := storage.SetupKey(sessionID, req.GetMessageId())
+	if err := g.s.s.SetValue(ctx, key, string(req.GetSetup())); err != nil {
+		return nil, g.grpcError("fail to store setup message", err)
+	}
+	if err := g.s.s.AddSessionKey(ctx, sessionID, key); err != nil {
+		return nil, g.grpcError("fail to track setup message", err)
+	}
+	return &relaypb.UploadSetupMessageResponse{}, nil
+}
+
+func (g *grpcService) GetSetupMessage(ctx context.Context, req *relaypb.GetSetupMessageRequest) (*relaypb.GetSetupMessageResponse, error) {
+	if err := g.checkToggles(false); err != nil {
+		return nil, err
+	}
+	sessionID, err := requireSessionID(req.GetSessionId())
+	if err != nil {
+		return nil, err
+	}
+	value, err := g.s.s.GetValue(ctx, storage.SetupKey(sessionID, req.GetMessageId()))
+	if err != nil {
+		return nil, g.grpcError("setup message not found", err)
+	}
+	return &relaypb.GetSetupMessageResponse{Setup: []byte(value)}, nil
+}
+
+// Exchange streams the inbox of a participant, and sends and acknowledges its messages.
+// The inbox is the one of the HTTP API, HTTP and gRPC participants exchange messages through storage.
+func (g *grpcService) Exchange(stream relaypb.RelayService_ExchangeServer) error {
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	inbox := first.GetOpen()
+	if inbox == nil {
+		return status.Error(codes.InvalidArgument, "the first request has to open an inbox")
+	}
+	sessionID, err := requireSessionID(inbox.GetSessionId())
+	if err != nil {
+		return err
+	}
+	participantID := strings.TrimSpace(inbox.GetParticipantId())
+	if participantID == "" {
+		return status.Error(codes.InvalidArgument, "participant ID is empty")
+	}
+	if err := g.checkToggles(false); err != nil {
+		return err
+	}
+	key := storage.MessageKey(sessionID, participantID, inbox.GetMessageId())
+
+	requests := make(chan *relaypb.ExchangeRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// sent are the hashes already streamed, a message stays in the inbox until it is acknowledged
+	sent := make(map[string]bool)
+	deliver := func() error {
+		messages, err := g.s.s.GetMessages(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return g.grpcError("fail to get messages", err)
+		}
+		for _, m := range messages {
+			if sent[m.Hash] {
+				continue
+			}
+			if err := stream.Send(&relaypb.ExchangeResponse{Message: toProtoMessage(m)}); err != nil {
+				return err
+			}
+			sent[m.Hash] = true
+		}
+		return nil
+	}
+	ticker := time.NewTicker(exchangePollInterval)
+	defer ticker.Stop()
+	if err := deliver(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-g.s.ctx.Done():
+			return status.Error(codes.Unavailable, "relay is shutting down")
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case req := <-requests:
+			if err := g.handleExchangeRequest(ctx, key, sessionID, inbox.GetMessageId(), req); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := g.checkToggles(false); err != nil {
+				return err
+			}
+			if err := deliver(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handleExchangeRequest sends or acknowledges a message of the Exchange stream of the inbox key.
+func (g *grpcService) handleExchangeRequest(ctx context.Context, key, sessionID, messageID string, req *relaypb.ExchangeRequest) error {
+	if err := g.checkToggles(true); err != nil {
+		return err
+	}
+	switch r := req.GetRequest().(type) {
+	case *relaypb.ExchangeRequest_Send:
+		m := fromProtoMessage(r.Send)
+		for _, item := range m.To {
+			key := storage.MessageKey(sessionID, item, messageID)
+			if err := g.s.s.SetMessage(ctx, key, m); err != nil {
+				return g.grpcError("fail to store message", err)
+			}
+			if err := g.s.s.AddSessionKey(ctx, sessionID, key); err != nil {
+				return g.grpcError("fail to track message", err)
+			}
+		}
+	case *relaypb.ExchangeRequest_Ack:
+		hash := strings.TrimSpace(r.Ack.GetHash())
+		if hash == "" {
+			return status.Error(codes.InvalidArgument, "message hash is empty")
+		}
+		if err := g.s.s.DeleteMessage(ctx, key, hash); err != nil {
+			return g.grpcError("fail to delete message", err)
+		}
+	case *relaypb.ExchangeRequest_Open:
+		return status.Error(codes.InvalidArgument, "the inbox is already open")
+	default:
+		return status.Error(codes.InvalidArgument, "empty request")
+	}
+	return nil
+}
+
+func toProtoMessage(m model.Message) *relaypb.Message {
+	return &relaypb.Message{
+		SessionId:  m.SessionID,
+		From:       m.From,
+		To:         m.To,
+		Body:       m.Body,
+		Hash:       m.Hash,
+		SequenceNo: m.SequenceNo,
+	}
+}
+
+func fromProtoMessage(m *relaypb.Message) model.Message {
+	return model.Message{
+		SessionID:  m.GetSessionId(),
+		From:       m.GetFrom(),
+		To:         m.GetTo(),
+		Body:       m.GetBody(),
+		Hash:       m.GetHash(),
+		SequenceNo: m.GetSequenceNo(),
+	}
+}
@@ -0,0 +1,287 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/relaypb"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// newGRPCClient serves the gRPC API of s in memory and returns a client of it.
+func newGRPCClient(t *testing.T, s *Server) relaypb.RelayServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer(s.grpcServerOptions()...)
+	s.RegisterGRPC(g)
+	go func() { _ = g.Serve(lis) }()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("fail to dial grpc server, err: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		g.Stop()
+	})
+	return relaypb.NewRelayServiceClient(conn)
+}
+
+// uploadPayload sends the payload over the UploadPayload stream in chunks of chunkSize bytes.
+func uploadPayload(ctx context.Context, client relaypb.RelayServiceClient, payload *relaypb.Payload, chunkSize int, sessionIDs ...string) error {
+	stream, err := client.UploadPayload(ctx)
+	if err != nil {
+		return err
+	}
+	data := payload.GetData()
+	req := &relaypb.UploadPayloadRequest{Payload: &relaypb.Payload{Hash: payload.GetHash()}, SessionIds: sessionIDs}
+	for {
+		n := min(chunkSize, len(data))
+		req.Payload.Data = data[:n]
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		data = data[n:]
+		if len(data) == 0 {
+			break
+		}
+		req = &relaypb.UploadPayloadRequest{Payload: &relaypb.Payload{}}
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// getPayload reads the GetPayload stream, it returns the hash of the first message and the data of all of them.
+func getPayload(ctx context.Context, client relaypb.RelayServiceClient, req *relaypb.GetPayloadRequest) (string, [][]byte, error) {
+	stream, err := client.GetPayload(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+	var hash string
+	var chunks [][]byte
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return hash, chunks, nil
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		if len(chunks) == 0 {
+			hash = resp.GetPayload().GetHash()
+		}
+		chunks = append(chunks, resp.GetPayload().GetData())
+	}
+}
+
+func serveHTTP(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestGRPCExchange runs a ceremony between a HTTP participant and a gRPC participant.
+func TestGRPCExchange(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	client := newGRPCClient(t, s)
+	ctx, cancel := context.WithTimeout(context.Background(), ceremonyTimeout)
+	defer cancel()
+
+	if _, err := client.JoinSession(ctx, &relaypb.JoinSessionRequest{Session: &relaypb.Session{SessionId: "s1", Participants: []string{"grpc"}}}); err != nil {
+		t.Fatalf("fail to join session, err: %v", err)
+	}
+	if rec := serveHTTP(handler, http.MethodPost, "/s1", `["http"]`); rec.Code != http.StatusCreated {
+		t.Fatalf("fail to join session over http, status: %d", rec.Code)
+	}
+	body := `{"session_id":"s1","from":"http","to":["grpc"],"body":"round 1","hash":"h1","sequence_no":1}`
+	if rec := serveHTTP(handler, http.MethodPost, "/message/s1", body); rec.Code != http.StatusAccepted {
+		t.Fatalf("fail to post message over http, status: %d", rec.Code)
+	}
+
+	stream, err := client.Exchange(ctx)
+	if err != nil {
+		t.Fatalf("fail to open exchange, err: %v", err)
+	}
+	if err := stream.Send(&relaypb.ExchangeRequest{Request: &relaypb.ExchangeRequest_Open{Open: &relaypb.Inbox{SessionId: "s1", ParticipantId: "grpc"}}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("fail to receive message, err: %v", err)
+	}
+	if m := resp.GetMessage(); m.GetFrom() != "http" || string(m.GetBody()) != "round 1" || m.GetHash() != "h1" || m.GetSequenceNo() != 1 {
+		t.Fatalf("unexpected message %v", m)
+	}
+	if err := stream.Send(&relaypb.ExchangeRequest{Request: &relaypb.ExchangeRequest_Ack{Ack: &relaypb.Ack{Hash: "h1"}}}); err != nil {
+		t.Fatal(err)
+	}
+	reply := &relaypb.Message{SessionId: "s1", From: "grpc", To: []string{"http"}, Body: []byte("round 2"), Hash: "h2", SequenceNo: 2}
+	if err := stream.Send(&relaypb.ExchangeRequest{Request: &relaypb.ExchangeRequest_Send{Send: reply}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the ack and the reply are handled in order, once the reply is delivered the ack is done
+	var messages []model.Message
+	err = poll("reply over http", func() (bool, error) {
+		rec := serveHTTP(handler, http.MethodGet, "/message/s1/http", "")
+		messages = nil
+		if rec.Body.Len() > 0 {
+			if err := json.Unmarshal(rec.Body.Bytes(), &messages); err != nil {
+				return false, err
+			}
+		}
+		return len(messages) > 0, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || messages[0].From != "grpc" || string(messages[0].Body) != "round 2" || messages[0].Hash != "h2" {
+		t.Fatalf("unexpected messages %+v", messages)
+	}
+	if rec := serveHTTP(handler, http.MethodGet, "/message/s1/grpc", ""); strings.Contains(rec.Body.String(), "h1") {
+		t.Fatalf("expected the acknowledged message to be removed, got %s", rec.Body)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGRPCUnary(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	client := newGRPCClient(t, s)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	data := []byte("keysign payload")
+	payload := &relaypb.Payload{Hash: hashOf(string(data)), Data: data}
+	if err := uploadPayload(ctx, client, payload, 4); err != nil {
+		t.Fatalf("fail to upload payload, err: %v", err)
+	}
+	if rec := serveHTTP(handler, http.MethodGet, "/payload/"+payload.Hash, ""); rec.Body.String() != string(data) {
+		t.Fatalf("expected the payload over http, got %d %q", rec.Code, rec.Body)
+	}
+	err := uploadPayload(ctx, client, &relaypb.Payload{Hash: payload.Hash, Data: []byte("other")}, 4)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected a hash mismatch to be refused, got %v", err)
+	}
+
+	scoped := &relaypb.Payload{Hash: hashOf("scoped payload"), Data: []byte("scoped payload")}
+	if err := uploadPayload(ctx, client, scoped, len(scoped.Data), "s1"); err != nil {
+		t.Fatalf("fail to upload payload, err: %v", err)
+	}
+	if _, _, err := getPayload(ctx, client, &relaypb.GetPayloadRequest{Hash: scoped.Hash}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected a payload stored for a session to be not found without it, got %v", err)
+	}
+	if rec := serveHTTP(handler, http.MethodPost, "/s1", `["a","b"]`); rec.Code != http.StatusCreated {
+		t.Fatalf("fail to create session over http, status: %d", rec.Code)
+	}
+	if _, got, err := getPayload(ctx, client, &relaypb.GetPayloadRequest{Hash: scoped.Hash, SessionId: "s1"}); err != nil || len(got) != 1 || string(got[0]) != "scoped payload" {
+		t.Fatalf("expected the payload with its session, err: %v", err)
+	}
+
+	if rec := serveHTTP(handler, http.MethodPost, "/start/s1", `["a","b"]`); rec.Code != http.StatusOK {
+		t.Fatalf("fail to start over http, status: %d", rec.Code)
+	}
+	marker, err := client.GetMarker(ctx, &relaypb.GetMarkerRequest{Marker: relaypb.Marker_MARKER_START, SessionId: "s1"})
+	if err != nil {
+		t.Fatalf("fail to get marker, err: %v", err)
+	}
+	if got := marker.GetSession().GetParticipants(); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("unexpected participants %v", got)
+	}
+	if _, err := client.GetMarker(ctx, &relaypb.GetMarkerRequest{SessionId: "s1"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected an unspecified marker to be refused, got %v", err)
+	}
+	if _, err := client.GetSetupMessage(ctx, &relaypb.GetSetupMessageRequest{SessionId: "s1"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected a missing setup message to be not found, got %v", err)
+	}
+
+	s.readOnly.Store(true)
+	if _, err := client.EndSession(ctx, &relaypb.EndSessionRequest{SessionId: "s1"}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected writes to be refused while read-only, got %v", err)
+	}
+	if _, _, err := getPayload(ctx, client, &relaypb.GetPayloadRequest{Hash: payload.Hash}); err != nil {
+		t.Fatalf("expected reads while read-only, got %v", err)
+	}
+}
+
+// TestGRPCPayloadChunks streams a payload larger than a stored chunk both ways.
+func TestGRPCPayloadChunks(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	client := newGRPCClient(t, s)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	data := bytes.Repeat([]byte("keysign payload "), storage.PayloadChunkSize/8)
+	payload := &relaypb.Payload{Hash: hashOf(string(data)), Data: data}
+	if err := uploadPayload(ctx, client, payload, 64<<10); err != nil {
+		t.Fatalf("fail to upload payload, err: %v", err)
+	}
+	if rec := serveHTTP(handler, http.MethodGet, "/payload/"+payload.Hash, ""); !bytes.Equal(rec.Body.Bytes(), data) {
+		t.Fatalf("expected the payload over http, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	hash, chunks, err := getPayload(ctx, client, &relaypb.GetPayloadRequest{Hash: payload.Hash})
+	if err != nil {
+		t.Fatalf("fail to get payload, err: %v", err)
+	}
+	if hash != payload.Hash || len(chunks) != 2 {
+		t.Fatalf("expected the hash %s and 2 chunks, got %s and %d chunks", payload.Hash, hash, len(chunks))
+	}
+	for _, chunk := range chunks {
+		if len(chunk) > storage.PayloadChunkSize {
+			t.Fatalf("expected chunks of at most %d bytes, got %d", storage.PayloadChunkSize, len(chunk))
+		}
+	}
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, data) {
+		t.Fatalf("expected the payload, got %d bytes", len(got))
+	}
+
+	stored := countChunks(t, s)
+	other := bytes.Repeat([]byte("other payload "), storage.PayloadChunkSize/8)
+	if err := uploadPayload(ctx, client, &relaypb.Payload{Hash: payload.Hash, Data: other}, 64<<10); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected a hash mismatch to be refused, got %v", err)
+	}
+	if n := countChunks(t, s); n != stored {
+		t.Fatalf("expected the chunks of a hash mismatch to be deleted, got %d chunks instead of %d", n, stored)
+	}
+	s.payload.MaxSize = int64(len(data)) - 1
+	if err := uploadPayload(ctx, client, &relaypb.Payload{Hash: hashOf(string(data) + "x"), Data: append(data, 'x')}, 64<<10); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected a payload over the size limit to be refused, got %v", err)
+	}
+	if n := countChunks(t, s); n != stored {
+		t.Fatalf("expected the chunks of a payload over the size limit to be deleted, got %d chunks instead of %d", n, stored)
+	}
+}
+
+// countChunks returns the number of payload chunks in the storage of the server.
+func countChunks(t *testing.T, s *Server) int {
+	t.Helper()
+	inspector, ok := storage.AsInspector(s.s)
+	if !ok {
+		t.Fatal("storage can't be inspected")
+	}
+	keys, err := inspector.Keys(context.Background(), storage.KindPrefix(storage.KindPayloadChunk))
+	if err != nil {
+		t.Fatalf("fail to list chunks, err: %v", err)
+	}
+	return len(keys)
+}
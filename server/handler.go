@@ -9,34 +9,130 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
+	"google.golang.org/grpc"
 
+	"github.com/vultisig/vultisig-relay/audit"
+	"github.com/vultisig/vultisig-relay/config"
 	"github.com/vultisig/vultisig-relay/contexthelper"
 	"github.com/vultisig/vultisig-relay/model"
 	"github.com/vultisig/vultisig-relay/storage"
 )
 
 type Server struct {
-	port int64
-	s    storage.Storage
-	e    *echo.Echo
+	port     int64
+	tls      config.TLS
+	shutdown config.Shutdown
+	s        storage.Storage
+	e        *echo.Echo
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// draining is set once shutdown starts, the relay stops accepting new sessions and reports not ready
+	draining atomic.Bool
+	// inFlight counts the relay requests and gRPC calls being served, lastActive is when the last one ended in unix nanoseconds
+	inFlight      atomic.Int64
+	lastActive    atomic.Int64
+	hooksLock     sync.Mutex
+	shutdownHooks []func(ctx context.Context) error
+	routesOnce    sync.Once
+	admin         config.Admin
+	auditor       *audit.Logger
+	// maintenance and readOnly are runtime toggles of the admin API, they are not shared between replicas
+	maintenance atomic.Bool
+	readOnly    atomic.Bool
+	grpc        config.GRPC
+	compression config.ResponseCompression
+	// valueTTL is how long the stored values are kept, payload uploads expire after it
+	valueTTL   time.Duration
+	payload    config.Payload
+	grpcLock   sync.Mutex
+	grpcServer *grpc.Server
+	metrics    config.Metrics
+	// metricsLock guards metricsServer, which is set once StartMetricsServer listens
+	metricsLock   sync.Mutex
+	metricsServer *http.Server
 }
 
 // NewServer returns a new server.
-func NewServer(port int64, s storage.Storage) *Server {
-	return &Server{
-		port: port,
-		s:    s,
-		e:    echo.New(),
-	}
+func NewServer(cfg *config.Config, s storage.Storage) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &Server{
+		port:        cfg.Port,
+		tls:         cfg.TLS,
+		shutdown:    cfg.Shutdown,
+		s:           s,
+		e:           echo.New(),
+		ctx:         ctx,
+		cancel:      cancel,
+		admin:       cfg.Admin,
+		auditor:     audit.New(os.Stdout),
+		grpc:        cfg.GRPC,
+		compression: cfg.ResponseCompression,
+		valueTTL:    cfg.Storage.Expiration.ValueTTL(),
+		payload:     cfg.Payload,
+		metrics:     cfg.Metrics,
+	}
+	server.OnShutdown(func(ctx context.Context) error {
+		return server.auditor.Close()
+	})
+	return server
+}
+
+// SetAuditLogger replaces the audit logger of the admin API, which writes to stdout by default.
+// The logger is closed when the server shuts down.
+func (s *Server) SetAuditLogger(l *audit.Logger) {
+	s.auditor = l
+}
+
+// OnShutdown registers a hook that runs after in-flight requests have drained,
+// it is used to flush anything buffered by the relay before storage is closed.
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) {
+	s.hooksLock.Lock()
+	defer s.hooksLock.Unlock()
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// Ready returns false once the server started draining.
+func (s *Server) Ready() bool {
+	return !s.draining.Load()
 }
 
 func (s *Server) StartServer() error {
+	s.setupRoutes()
+	addr := fmt.Sprintf(":%d", s.port)
+	if !s.tls.Enabled() {
+		return s.e.Start(addr)
+	}
+	reloader, err := newCertReloader(s.tls)
+	if err != nil {
+		return fmt.Errorf("fail to load tls config, err: %w", err)
+	}
+	go reloader.watch(s.ctx, s.e.Logger)
+	s.e.TLSServer.Addr = addr
+	s.e.TLSServer.TLSConfig = reloader.TLSConfig()
+	return s.e.StartServer(s.e.TLSServer)
+}
+
+// Handler returns the http handler of the relay, to serve it without StartServer, e.g. from httptest.
+func (s *Server) Handler() http.Handler {
+	s.setupRoutes()
+	return s.e
+}
+
+// setupRoutes registers the middlewares and the routes of the relay, once.
+func (s *Server) setupRoutes() {
+	s.routesOnce.Do(s.registerRoutes)
+}
+
+func (s *Server) registerRoutes() {
 	e := s.e
 	e.Logger.SetLevel(log.DEBUG)
 	e.Pre(middleware.RemoveTrailingSlash())
@@ -45,32 +141,124 @@ func (s *Server) StartServer() error {
 	// enable cors
 	e.Use(middleware.CORS())
 	e.Use(middleware.BodyLimit("100M")) // set maximum allowed size for a request body to 100M
+	if s.compression.Enabled {
+		e.Use(compressResponses(int(s.compression.MinSize)))
+	}
 	e.GET("/ping", s.Ping)
-	group := e.Group("")
-	group.POST("/:sessionID", s.StartSession)
-	group.GET("/:sessionID", s.GetSession)
-	group.DELETE("/:sessionID", s.DeleteSession)
-	group.POST("/message/:sessionID", s.PostMessage)
-	group.GET("/message/:sessionID/:participantID", s.GetMessage)
-	group.DELETE("/message/:sessionID/:participantID/:hash", s.DeleteMessage)
-	group.POST("/start/:sessionID", s.StartTSSSession)
-	group.GET("/start/:sessionID", s.GetStartTSSSession)
-	group.POST("/complete/:sessionID", s.SetCompleteTSSSession)
-	group.GET("/complete/:sessionID", s.GetCompleteTSSSession)
-	group.POST("/complete/:sessionID/keysign", s.SetKeysignFinished)
-	group.GET("/complete/:sessionID/keysign", s.GetKeysignFinished)
-	group.POST("/payload/:hash", s.HandlePayloadMessage)
-	group.GET("/payload/:hash", s.GetPayloadMessage)
-	group.POST("/setup-message/:sessionID", s.PostSetupMessage)
-	group.GET("/setup-message/:sessionID", s.GetSetupMessage)
-	return e.Start(fmt.Sprintf(":%d", s.port))
-}
-
-func (s *Server) StopServer() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	e.GET("/healthz", s.Healthz)
+	e.GET("/readyz", s.Readyz)
+	e.GET("/openapi.json", s.OpenAPI)
+	if s.admin.Enabled() {
+		s.registerAdminRoutes(e.Group("/admin", s.auditAdmin, s.authenticateAdmin))
+	}
+	s.registerRelayRoutes(e.Group("", v1Errors, s.guardToggles, s.trackRequests))
+	// the v2 middlewares are set per route, group middlewares would also catch GET /v2, the session named v2
+	v2 := e.Group(v2Prefix)
+	v2Middlewares := []echo.MiddlewareFunc{middleware.RequestID(), v2Errors}
+	v2.RouteNotFound("/*", echo.NotFoundHandler, v2Middlewares...)
+	s.registerRelayRoutes(v2, append(v2Middlewares, s.guardToggles, s.trackRequests)...)
+}
+
+// v2Prefix is the prefix of the v2 routes, they are the relay routes with JSON error responses.
+const v2Prefix = "/v2"
+
+func (s *Server) registerRelayRoutes(group *echo.Group, m ...echo.MiddlewareFunc) {
+	group.POST("/:sessionID", s.StartSession, m...)
+	group.GET("/:sessionID", s.GetSession, m...)
+	group.DELETE("/:sessionID", s.DeleteSession, m...)
+	group.POST("/message/:sessionID", s.PostMessage, m...)
+	group.GET("/message/:sessionID/:participantID", s.GetMessage, m...)
+	group.DELETE("/message/:sessionID/:participantID/:hash", s.DeleteMessage, m...)
+	group.POST("/start/:sessionID", s.StartTSSSession, m...)
+	group.GET("/start/:sessionID", s.GetStartTSSSession, m...)
+	group.POST("/complete/:sessionID", s.SetCompleteTSSSession, m...)
+	group.GET("/complete/:sessionID", s.GetCompleteTSSSession, m...)
+	group.POST("/complete/:sessionID/keysign", s.SetKeysignFinished, m...)
+	group.GET("/complete/:sessionID/keysign", s.GetKeysignFinished, m...)
+	group.POST("/payload/:hash", s.HandlePayloadMessage, m...)
+	group.GET("/payload/:hash", s.GetPayloadMessage, m...)
+	group.POST("/payload/:hash/upload", s.CreatePayloadUpload, m...)
+	group.GET("/payload/:hash/upload/:uploadID", s.GetPayloadUpload, m...)
+	group.PUT("/payload/:hash/upload/:uploadID", s.PutPayloadChunk, m...)
+	group.POST("/payload/:hash/upload/:uploadID", s.CompletePayloadUpload, m...)
+	group.POST("/setup-message/:sessionID", s.PostSetupMessage, m...)
+	group.GET("/setup-message/:sessionID", s.GetSetupMessage, m...)
+}
+
+// drainIdle is how long no relay request has to be served for draining to end before the grace period,
+// participants of an ongoing session poll more often than that.
+const drainIdle = time.Second * 2
+
+// drainPollInterval is how often draining checks whether the relay is idle.
+const drainPollInterval = time.Millisecond * 100
+
+// StopServer drains the server: it stops accepting new sessions and keeps serving existing ones for the grace period,
+// then closes the listener and waits for in-flight requests up to the shutdown timeout, and finally runs the shutdown hooks.
+// The grace period ends early once the relay is idle, or when ctx is cancelled.
+func (s *Server) StopServer(ctx context.Context) error {
+	s.draining.Store(true)
+	s.e.Logger.Infof("draining, grace period %s", time.Duration(s.shutdown.GracePeriod))
+	s.drain(ctx, time.Duration(s.shutdown.GracePeriod))
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.shutdown.Timeout))
 	defer cancel()
-	return s.e.Shutdown(ctx)
+	err := s.e.Shutdown(ctx)
+	if err != nil {
+		err = fmt.Errorf("fail to drain in-flight requests, err: %w", err)
+	}
+	s.stopGRPCServer(ctx)
+	if metricsErr := s.stopMetricsServer(ctx); metricsErr != nil {
+		err = errors.Join(err, fmt.Errorf("fail to stop metrics server, err: %w", metricsErr))
+	}
+	s.hooksLock.Lock()
+	defer s.hooksLock.Unlock()
+	for _, hook := range s.shutdownHooks {
+		if hookErr := hook(ctx); hookErr != nil {
+			err = errors.Join(err, fmt.Errorf("fail to run shutdown hook, err: %w", hookErr))
+		}
+	}
+	return err
+}
+
+// drain waits until no relay request was served for drainIdle, the grace period is over or ctx is cancelled.
+func (s *Server) drain(ctx context.Context, gracePeriod time.Duration) {
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for !s.idle() {
+		select {
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
+
+// idle returns whether no relay request is in flight and none ended within drainIdle.
+func (s *Server) idle() bool {
+	return s.inFlight.Load() == 0 && time.Since(time.Unix(0, s.lastActive.Load())) >= drainIdle
+}
+
+// track counts a request as in flight until the returned function is called.
+func (s *Server) track() func() {
+	s.inFlight.Add(1)
+	return func() {
+		s.lastActive.Store(time.Now().UnixNano())
+		s.inFlight.Add(-1)
+	}
+}
+
+// trackRequests counts the relay requests in flight, health probes and the admin API don't delay draining.
+func (s *Server) trackRequests(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		defer s.track()()
+		return next(c)
+	}
+}
+
 func (s *Server) Ping(c echo.Context) error {
 	return c.String(http.StatusOK, "Voltix Router is running")
 }
@@ -79,15 +267,24 @@ func (s *Server) Ping(c echo.Context) error {
 func (s *Server) StartSession(c echo.Context) error {
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
 	var p []string
 	if err := c.Bind(&p); err != nil {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidBody, "participants must be a JSON array of strings", err)
+	}
+	if s.draining.Load() {
+		// while draining, participants can still join existing sessions, but no new session is created
+		existing, err := s.s.GetSession(c.Request().Context(), storage.SessionKey(sessionID))
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return storageError("fail to get session", err)
+		}
+		if len(existing) == 0 {
+			return unavailable(CodeDraining, "relay is shutting down, new sessions are refused")
+		}
 	}
-	if err := s.s.SetSession(c.Request().Context(), sessionID, p); err != nil {
-		c.Logger().Error(err)
-		return c.NoContent(http.StatusInternalServerError)
+	if err := s.s.SetSession(c.Request().Context(), storage.SessionKey(sessionID), p); err != nil {
+		return storageError("fail to set session", err)
 	}
 	return c.NoContent(http.StatusCreated)
 }
@@ -95,288 +292,348 @@ func (s *Server) StartSession(c echo.Context) error {
 func (s *Server) GetSession(c echo.Context) error {
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
-	p, err := s.s.GetSession(c.Request().Context(), sessionID)
+	p, err := s.s.GetSession(c.Request().Context(), storage.SessionKey(sessionID))
 	if err != nil {
-		return c.NoContent(http.StatusNotFound)
+		return lookupError(CodeSessionNotFound, "session not found", err)
 	}
 	return c.JSON(http.StatusOK, p)
 }
 
-// DeleteSession is to end a session. Remove all relevant messages
+// DeleteSession is to end a session. Remove all relevant messages, markers and setup messages
 func (s *Server) DeleteSession(c echo.Context) error {
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
+	}
+	if err := storage.ReleaseSessionPayloads(c.Request().Context(), s.s, sessionID); err != nil {
+		return storageError(fmt.Sprintf("fail to release payloads of session %s", sessionID), err)
 	}
 	if err := s.s.DeleteSession(c.Request().Context(), sessionID); err != nil { // delete session
-		c.Logger().Errorf("fail to delete session %s,err: %s", sessionID, err)
-		return c.NoContent(http.StatusInternalServerError)
+		return storageError(fmt.Sprintf("fail to delete session %s", sessionID), err)
 	}
 	return c.NoContent(http.StatusOK)
 }
 
+// lookupError is the error of a failed read. The routes without version answer 404 whatever the cause,
+// the v2 routes tell a missing key from a storage failure.
+func lookupError(code, message string, err error) *apiError {
+	if errors.Is(err, storage.ErrNotFound) {
+		return notFound(code, message)
+	}
+	ae := storageError(message, err)
+	ae.v1Status = http.StatusNotFound
+	return ae
+}
+
+// participantParam returns the participant ID of the path, which clients query escape.
+func participantParam(c echo.Context) (string, error) {
+	rawParticipantID, err := url.QueryUnescape(c.Param("participantID"))
+	if err != nil {
+		return "", badRequest(CodeInvalidParticipantID, "participant ID is not query escaped", err)
+	}
+	return strings.TrimSpace(rawParticipantID), nil
+}
+
 func (s *Server) GetMessage(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
-	rawParticipantID, err := url.QueryUnescape(c.Param("participantID"))
+	participantID, err := participantParam(c)
 	if err != nil {
-		c.Logger().Errorf("fail to unescape participant ID %s, err: %s", c.Param("participantID"), err)
-		return c.NoContent(http.StatusBadRequest)
+		return err
+	}
+	if sessionID == "" {
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
-	participantID := strings.TrimSpace(rawParticipantID)
-	if sessionID == "" || participantID == "" {
-		return c.NoContent(http.StatusBadRequest)
+	if participantID == "" {
+		return badRequest(CodeInvalidParticipantID, "participant ID is empty", nil)
 	}
 	messageID := c.Request().Header.Get("message_id")
 	c.Logger().Debug("session ID is ", sessionID, ", participant ID is ", participantID, ", message ID is ", messageID)
-	key := fmt.Sprintf("%s-%s", sessionID, participantID)
-	if messageID != "" {
-		key = fmt.Sprintf("%s-%s-%s", sessionID, participantID, messageID)
-	}
+	key := storage.MessageKey(sessionID, participantID, messageID)
 	messages, err := s.s.GetMessages(c.Request().Context(), key)
 	if errors.Is(err, storage.ErrNotFound) {
 		return c.NoContent(http.StatusOK)
 	}
+	if err != nil && isV2(c) {
+		return storageError("fail to get messages", err)
+	}
+	if err != nil {
+		// the route without version answers an empty inbox when storage fails, old apps keep polling
+		c.Logger().Errorf("fail to get messages %s, err: %s", key, err)
+	}
 	if messages == nil {
 		messages = []model.Message{}
 	}
-	return c.JSON(http.StatusOK, messages)
+	return writeMessages(c, messages)
 }
 
 // DeleteMessage is to delete a message.
 func (s *Server) DeleteMessage(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
-	rawParticipantID, err := url.QueryUnescape(c.Param("participantID"))
+	participantID, err := participantParam(c)
 	if err != nil {
-		c.Logger().Errorf("fail to unescape participant ID %s, err: %s", c.Param("participantID"), err)
-		return c.NoContent(http.StatusBadRequest)
+		return err
 	}
-	participantID := strings.TrimSpace(rawParticipantID)
 	messageID := c.Request().Header.Get("message_id")
 	msgHash := strings.TrimSpace(c.Param("hash"))
-	if sessionID == "" || participantID == "" || msgHash == "" {
-		return c.NoContent(http.StatusBadRequest)
-	}
-	key := fmt.Sprintf("%s-%s", sessionID, participantID)
-	if messageID != "" {
-		key = fmt.Sprintf("%s-%s-%s", sessionID, participantID, messageID)
-	}
+	switch {
+	case sessionID == "":
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
+	case participantID == "":
+		return badRequest(CodeInvalidParticipantID, "participant ID is empty", nil)
+	case msgHash == "":
+		return badRequest(CodeInvalidHash, "message hash is empty", nil)
+	}
+	key := storage.MessageKey(sessionID, participantID, messageID)
 	if err := s.s.DeleteMessage(c.Request().Context(), key, msgHash); err != nil {
-		c.Logger().Errorf("fail to delete message %s, err: %s", key, err)
-		return c.NoContent(http.StatusInternalServerError)
+		return storageError(fmt.Sprintf("fail to delete message %s", key), err)
 	}
 	return c.NoContent(http.StatusOK)
 }
 
 func (s *Server) PostMessage(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		c.Logger().Error("session ID is empty")
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
 	c.Logger().Debug("session ID is ", sessionID)
 	messageID := c.Request().Header.Get("message_id")
 	var m model.Message
-	if err := c.Bind(&m); err != nil {
-		c.Logger().Error(err)
-		return c.NoContent(http.StatusBadRequest)
+	if err := bindMessage(c, &m); err != nil {
+		return err
 	}
 	for _, item := range m.To {
-		key := fmt.Sprintf("%s-%s", sessionID, item)
-		if messageID != "" {
-			key = fmt.Sprintf("%s-%s-%s", sessionID, item, messageID)
-		}
+		key := storage.MessageKey(sessionID, item, messageID)
 		if err := s.s.SetMessage(c.Request().Context(), key, m); err != nil {
-			c.Logger().Error(err)
-			return c.NoContent(http.StatusInternalServerError)
+			return storageError("fail to store message", err)
+		}
+		if err := s.s.AddSessionKey(c.Request().Context(), sessionID, key); err != nil {
+			return storageError("fail to track message", err)
 		}
 	}
 	return c.NoContent(http.StatusAccepted)
 }
-func (s *Server) handleTSSSession(c echo.Context, sessionPrefix string) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+func (s *Server) handleTSSSession(c echo.Context, keyFunc func(sessionID string) string) error {
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
 	var p []string
 	if err := c.Bind(&p); err != nil {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidBody, "participants must be a JSON array of strings", err)
 	}
-	key := fmt.Sprintf("%s-%s", sessionPrefix, sessionID)
+	key := keyFunc(sessionID)
 	if err := s.s.SetSession(c.Request().Context(), key, p); err != nil {
-		c.Logger().Error(err)
-		return c.NoContent(http.StatusInternalServerError)
+		return storageError("fail to set participants", err)
+	}
+	if err := s.s.AddSessionKey(c.Request().Context(), sessionID, key); err != nil {
+		return storageError("fail to track participants", err)
 	}
 	return c.NoContent(http.StatusOK)
 }
-func (s *Server) getTSSSession(c echo.Context, sessionPrefix string) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+func (s *Server) getTSSSession(c echo.Context, keyFunc func(sessionID string) string) error {
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
-	key := fmt.Sprintf("%s-%s", sessionPrefix, sessionID)
+	key := keyFunc(sessionID)
 	participants, err := s.s.GetSession(c.Request().Context(), key)
 	if err != nil {
-		return c.NoContent(http.StatusNotFound)
+		return lookupError(CodeNotFound, "participants not found", err)
 	}
 	return c.JSON(http.StatusOK, participants)
 }
 
 func (s *Server) StartTSSSession(c echo.Context) error {
-	return s.handleTSSSession(c, "start")
+	return s.handleTSSSession(c, storage.StartKey)
 }
 
 func (s *Server) GetStartTSSSession(c echo.Context) error {
-	return s.getTSSSession(c, "start")
+	return s.getTSSSession(c, storage.StartKey)
 }
 
 func (s *Server) SetCompleteTSSSession(c echo.Context) error {
-	return s.handleTSSSession(c, "complete")
+	return s.handleTSSSession(c, storage.CompleteKey)
 }
 
 func (s *Server) GetCompleteTSSSession(c echo.Context) error {
-	return s.getTSSSession(c, "complete")
+	return s.getTSSSession(c, storage.CompleteKey)
 }
 func (s *Server) SetKeysignFinished(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
 	messageID := c.Request().Header.Get("message_id")
-	key := fmt.Sprintf("keysign-%s-%s-complete", sessionID, messageID)
+	key := storage.KeysignCompleteKey(sessionID, messageID)
 	input, err := io.ReadAll(c.Request().Body)
 	if err != nil {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidBody, "fail to read body", err)
+	}
+	if err := s.s.SetValue(c.Request().Context(), key, string(input)); err != nil {
+		return storageError("fail to store keysign result", err)
 	}
-	if s.s.SetValue(c.Request().Context(), key, string(input)) != nil {
-		return c.NoContent(http.StatusInternalServerError)
+	if err := s.s.AddSessionKey(c.Request().Context(), sessionID, key); err != nil {
+		return storageError("fail to track keysign result", err)
 	}
 	return c.NoContent(http.StatusOK)
 }
 func (s *Server) GetKeysignFinished(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
 	messageID := c.Request().Header.Get("message_id")
-	key := fmt.Sprintf("keysign-%s-%s-complete", sessionID, messageID)
+	key := storage.KeysignCompleteKey(sessionID, messageID)
 	value, err := s.s.GetValue(c.Request().Context(), key)
 	if err != nil {
-		return c.NoContent(http.StatusNotFound)
+		return lookupError(CodeNotFound, "keysign result not found", err)
 	}
 	return c.String(http.StatusOK, value)
 }
 
+// HandlePayloadMessage stores a payload as it is read, hashing it and storing it in chunks, so it is never held whole
+// in memory. The payload is only visible once it matched its ID. With session_id query parameters the payload is
+// referenced by those sessions, and deleted once all of them are deleted.
 func (s *Server) HandlePayloadMessage(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
-	hash := strings.TrimSpace(c.Param("hash"))
-	if hash == "" {
-		return c.NoContent(http.StatusBadRequest)
+	raw := strings.TrimSpace(c.Param("hash"))
+	if raw == "" {
+		return badRequest(CodeInvalidHash, "payload hash is empty", nil)
 	}
-	input, err := io.ReadAll(c.Request().Body)
+	payloadID, err := storage.ParsePayloadID(raw)
 	if err != nil {
-		c.Logger().Error(err)
-		return c.NoContent(http.StatusBadRequest)
+		if !strings.Contains(raw, ":") {
+			// a digest without algorithm is a SHA-256, no payload matches a malformed one
+			return badRequest(CodeHashMismatch, "hash does not match, expected a hex encoded SHA-256", err)
+		}
+		return badRequest(CodeInvalidHash, "invalid payload ID", err)
+	}
+	sessions := payloadSessions(c.QueryParams()["session_id"])
+	tooLarge := &apiError{status: http.StatusRequestEntityTooLarge, code: CodeRequestTooLarge, message: fmt.Sprintf("payload exceeds %d bytes", s.payload.MaxSize)}
+	if c.Request().ContentLength > s.payload.MaxSize {
+		return tooLarge
+	}
+	id, err := storage.NewPayloadID()
+	if err != nil {
+		return &apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "fail to generate payload ID", err: err}
+	}
+	canonical, h := sha256.New(), payloadID.NewHash()
+	w := storage.NewPayloadWriter(c.Request().Context(), s.s, storage.PayloadManifest{ID: id})
+	stored := false
+	defer func() {
+		if !stored {
+			// the chunks of a rejected payload are deleted, they expire when they can't be
+			_ = w.Discard()
+		}
+	}()
+	n, err := io.Copy(w, io.TeeReader(io.LimitReader(c.Request().Body, s.payload.MaxSize+1), io.MultiWriter(canonical, h)))
+	if err != nil {
+		if w.Err() != nil {
+			return storageError("fail to store payload", err)
+		}
+		return badRequest(CodeInvalidBody, "fail to read body", err)
+	}
+	if n > s.payload.MaxSize {
+		return tooLarge
 	}
-	h := sha256.New()
-	h.Write(input)
 	result := hex.EncodeToString(h.Sum(nil))
-	if result != hash {
-		c.Logger().Error("hash does not match, expected ", hash, ", got ", result)
-		return c.NoContent(http.StatusBadRequest)
+	if result != payloadID.Digest {
+		return badRequest(CodeHashMismatch, fmt.Sprintf("hash does not match, expected %s, got %s", payloadID.Digest, result), nil)
 	}
-
-	if err := s.s.SetValue(c.Request().Context(), result, string(input)); err != nil {
-		return c.NoContent(http.StatusInternalServerError)
+	if err := w.Flush(); err != nil {
+		return storageError("fail to store payload", err)
+	}
+	// once the payload is stored its chunks may be shared, they are left to expire when storing fails
+	stored = true
+	hash := hex.EncodeToString(canonical.Sum(nil))
+	if err := storage.StorePayload(c.Request().Context(), s.s, payloadID, hash, w.Manifest(), sessions); err != nil {
+		return storageError("fail to store payload", err)
 	}
 	return c.NoContent(http.StatusOK)
 }
 
+// GetPayloadMessage streams a payload, its chunks are verified against their hash before they are sent.
+// A payload stored for sessions is only sent with the session_id query parameter of one of them.
 func (s *Server) GetPayloadMessage(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
-	hash := strings.TrimSpace(c.Param("hash"))
-	if hash == "" {
-		return c.NoContent(http.StatusBadRequest)
-	}
-	value, err := s.s.GetValue(c.Request().Context(), hash)
+	payloadID, err := storage.ParsePayloadID(c.Param("hash"))
 	if err != nil {
-		return c.NoContent(http.StatusNotFound)
+		// no payload is stored with an invalid ID
+		return notFound(CodeNotFound, "payload not found")
 	}
-	h := sha256.New()
-	h.Write([]byte(value))
-	result := hex.EncodeToString(h.Sum(nil))
-	if result != hash {
-		return c.NoContent(http.StatusInternalServerError)
+	if err := checkPayloadScope(c.Request().Context(), s.s, payloadID, strings.TrimSpace(c.QueryParam("session_id"))); err != nil {
+		return lookupError(CodeNotFound, "payload not found", err)
 	}
-	return c.String(http.StatusOK, value)
+	payload, err := storage.GetPayload(c.Request().Context(), s.s, payloadID)
+	if err != nil {
+		return payloadError(err)
+	}
+	return writePayload(c, payloadID.String(), payload)
 }
 
 func (s *Server) PostSetupMessage(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
-	key := fmt.Sprintf("setup-%s", sessionID)
 	messageID := c.Request().Header.Get("message_id")
-	if messageID != "" {
-		key = fmt.Sprintf("setup-%s-%s", sessionID, messageID)
-	}
+	key := storage.SetupKey(sessionID, messageID)
 	input, err := io.ReadAll(c.Request().Body)
 	if err != nil {
-		c.Logger().Error(err)
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidBody, "fail to read body", err)
 	}
-	if s.s.SetValue(c.Request().Context(), key, string(input)) != nil {
-		return c.NoContent(http.StatusInternalServerError)
+	if err := s.s.SetValue(c.Request().Context(), key, string(input)); err != nil {
+		return storageError("fail to store setup message", err)
+	}
+	if err := s.s.AddSessionKey(c.Request().Context(), sessionID, key); err != nil {
+		return storageError("fail to track setup message", err)
 	}
 	return c.NoContent(http.StatusCreated)
 }
 
 func (s *Server) GetSetupMessage(c echo.Context) error {
-	if contexthelper.CheckCancellation(c.Request().Context()) != nil {
-		return c.NoContent(http.StatusRequestTimeout)
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
 	}
 	sessionID := strings.TrimSpace(c.Param("sessionID"))
 	if sessionID == "" {
-		return c.NoContent(http.StatusBadRequest)
+		return badRequest(CodeInvalidSessionID, "session ID is empty", nil)
 	}
-	key := fmt.Sprintf("setup-%s", sessionID)
 	messageID := c.Request().Header.Get("message_id")
-	if messageID != "" {
-		key = fmt.Sprintf("setup-%s-%s", sessionID, messageID)
-	}
+	key := storage.SetupKey(sessionID, messageID)
 	value, err := s.s.GetValue(c.Request().Context(), key)
 	if err != nil {
-		return c.NoContent(http.StatusNotFound)
+		return lookupError(CodeNotFound, "setup message not found", err)
 	}
 	return c.String(http.StatusOK, value)
 }
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/relaypb"
+)
+
+// startDraining runs StopServer in the background and waits until the server reports itself as draining.
+func startDraining(t *testing.T, s *Server, ctx context.Context) <-chan error {
+	t.Helper()
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.StopServer(ctx) }()
+	if err := poll("draining", func() (bool, error) { return !s.Ready(), nil }); err != nil {
+		t.Fatal(err)
+	}
+	return stopped
+}
+
+func waitStopped(t *testing.T, stopped <-chan error, timeout time.Duration) {
+	t.Helper()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatalf("fail to stop server, err: %v", err)
+		}
+	case <-time.After(timeout):
+		t.Fatalf("server didn't stop within %s", timeout)
+	}
+}
+
+func TestDrainSessions(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	client := newGRPCClient(t, s)
+	s.shutdown.GracePeriod = config.Duration(time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if rec := serveHTTP(handler, http.MethodPost, "/existing", `["a"]`); rec.Code != http.StatusCreated {
+		t.Fatalf("fail to create session, status: %d", rec.Code)
+	}
+
+	// an ongoing request keeps the relay draining until it ends
+	done := s.track()
+	stopped := startDraining(t, s, context.Background())
+
+	for _, path := range []string{"/new", "/v2/new"} {
+		rec := serveHTTP(handler, http.MethodPost, path, `["a"]`)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("POST %s: expected a new session to be refused, got %d", path, rec.Code)
+		}
+		if path == "/v2/new" && !strings.Contains(rec.Body.String(), CodeDraining) {
+			t.Fatalf("POST %s: expected the %s code, got %s", path, CodeDraining, rec.Body)
+		}
+	}
+	if rec := serveHTTP(handler, http.MethodPost, "/existing", `["b"]`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected joining an existing session to work, got %d", rec.Code)
+	}
+	if rec := serveHTTP(handler, http.MethodGet, "/existing", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"b"`) {
+		t.Fatalf("expected the existing session to be served, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := serveHTTP(handler, http.MethodGet, "/readyz", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the relay not to be ready, got %d", rec.Code)
+	}
+
+	_, err := client.JoinSession(ctx, &relaypb.JoinSessionRequest{Session: &relaypb.Session{SessionId: "grpc-new", Participants: []string{"a"}}})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected a new session to be refused over grpc, got %v", err)
+	}
+	if _, err := client.JoinSession(ctx, &relaypb.JoinSessionRequest{Session: &relaypb.Session{SessionId: "existing", Participants: []string{"c"}}}); err != nil {
+		t.Fatalf("expected joining an existing session over grpc to work, err: %v", err)
+	}
+	resp, err := client.GetSession(ctx, &relaypb.GetSessionRequest{SessionId: "existing"})
+	if err != nil || !containsAll(resp.GetSession().GetParticipants(), []string{"a", "b", "c"}) {
+		t.Fatalf("expected the existing session over grpc, got %v, err: %v", resp.GetSession().GetParticipants(), err)
+	}
+
+	select {
+	case <-stopped:
+		t.Fatal("expected the server to keep draining while a request is in flight")
+	default:
+	}
+	done()
+	waitStopped(t, stopped, drainIdle+time.Second*2)
+}
+
+func TestDrainEndsEarly(t *testing.T) {
+	t.Run("idle", func(t *testing.T) {
+		s, _ := newTestServer(t, nil)
+		s.shutdown.GracePeriod = config.Duration(time.Minute)
+		start := time.Now()
+		if err := s.StopServer(context.Background()); err != nil {
+			t.Fatalf("fail to stop server, err: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("expected an idle relay to stop at once, took %s", elapsed)
+		}
+	})
+	t.Run("grace period", func(t *testing.T) {
+		s, _ := newTestServer(t, nil)
+		s.shutdown.GracePeriod = config.Duration(time.Millisecond * 200)
+		done := s.track()
+		defer done()
+		start := time.Now()
+		if err := s.StopServer(context.Background()); err != nil {
+			t.Fatalf("fail to stop server, err: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < time.Millisecond*200 || elapsed > drainIdle {
+			t.Fatalf("expected the relay to stop after the grace period, took %s", elapsed)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		s, _ := newTestServer(t, nil)
+		s.shutdown.GracePeriod = config.Duration(time.Minute)
+		done := s.track()
+		defer done()
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := startDraining(t, s, ctx)
+		cancel()
+		waitStopped(t, stopped, time.Second)
+	})
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// readyTimeout is the maximum time the readiness check waits for the storage backend.
+const readyTimeout = time.Second * 2
+
+type healthResponse struct {
+	Status    string  `json:"status"`
+	Backend   string  `json:"backend,omitempty"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Healthz reports that the process is alive, it doesn't check any dependency.
+func (s *Server) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Readyz reports whether the relay can serve traffic: it is not draining and the storage backend answers in time.
+func (s *Server) Readyz(c echo.Context) error {
+	resp := healthResponse{
+		Backend: s.s.Type(),
+	}
+	if !s.Ready() {
+		resp.Status = "draining"
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), readyTimeout)
+	defer cancel()
+	start := time.Now()
+	err := s.s.Ping(ctx)
+	resp.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		c.Logger().Errorf("readiness check failed, err: %s", err)
+		resp.Status = "unavailable"
+		// the error can name hosts of the storage backend, it is only logged
+		resp.Error = "storage unavailable"
+		return c.JSON(http.StatusServiceUnavailable, resp)
+	}
+	resp.Status = "ok"
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// pingStorage answers Ping with ping.
+type pingStorage struct {
+	storage.Storage
+	ping func(ctx context.Context) error
+}
+
+func (s pingStorage) Ping(ctx context.Context) error {
+	return s.ping(ctx)
+}
+
+// newHealthServer returns a server whose storage answers Ping with ping, or the in-memory storage when ping is nil.
+func newHealthServer(t *testing.T, ping func(ctx context.Context) error) (*Server, http.Handler) {
+	t.Helper()
+	var store storage.Storage
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatalf("fail to create storage, err: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if ping != nil {
+		store = pingStorage{Storage: store, ping: ping}
+	}
+	cfg := config.DefaultConfig()
+	s := NewServer(&cfg, store)
+	handler := s.Handler()
+	s.e.Logger.SetOutput(io.Discard)
+	return s, handler
+}
+
+func TestHealth(t *testing.T) {
+	hostErr := errors.New("dial tcp redis.internal:6379: connection refused")
+	tests := []struct {
+		name     string
+		ping     func(ctx context.Context) error
+		draining bool
+		// timeout is the deadline of the readiness request, the storage doesn't answer before it
+		timeout    time.Duration
+		wantStatus int
+		want       healthResponse
+	}{
+		{name: "healthy", wantStatus: http.StatusOK, want: healthResponse{Status: "ok", Backend: storage.TypeMemory}},
+		{name: "ping fails", ping: func(context.Context) error { return hostErr }, wantStatus: http.StatusServiceUnavailable,
+			want: healthResponse{Status: "unavailable", Backend: storage.TypeMemory, Error: "storage unavailable"}},
+		{name: "ping times out", ping: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }, timeout: time.Millisecond * 50, wantStatus: http.StatusServiceUnavailable,
+			want: healthResponse{Status: "unavailable", Backend: storage.TypeMemory, Error: "storage unavailable"}},
+		{name: "draining", draining: true, wantStatus: http.StatusServiceUnavailable,
+			want: healthResponse{Status: "draining", Backend: storage.TypeMemory}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, handler := newHealthServer(t, tt.ping)
+			s.draining.Store(tt.draining)
+
+			// liveness doesn't depend on storage or draining
+			if rec := serveHTTP(handler, http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK || rec.Body.String() != "{\"status\":\"ok\"}\n" {
+				t.Fatalf("expected /healthz to be ok, got %d %s", rec.Code, rec.Body)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			if tt.timeout > 0 {
+				ctx, cancel := context.WithTimeout(req.Context(), tt.timeout)
+				defer cancel()
+				req = req.WithContext(ctx)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			var got healthResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("fail to decode %s, err: %v", rec.Body, err)
+			}
+			// the latency varies, and the error of the storage, which names its hosts, is never sent
+			got.LatencyMs = 0
+			if got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
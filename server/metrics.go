@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler returns the http handler of the Prometheus metrics.
+func (s *Server) MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// StartMetricsServer serves the metrics on the metrics port, apart from the relay so they are not public.
+func (s *Server) StartMetricsServer() error {
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.metrics.Port),
+		Handler: s.MetricsHandler(),
+	}
+	s.metricsLock.Lock()
+	if s.ctx.Err() != nil {
+		// the relay shut down before the metrics were served
+		s.metricsLock.Unlock()
+		return http.ErrServerClosed
+	}
+	s.metricsServer = srv
+	s.metricsLock.Unlock()
+	return srv.ListenAndServe()
+}
+
+// stopMetricsServer closes the metrics listener and waits for the scrapes in flight until ctx is done.
+func (s *Server) stopMetricsServer(ctx context.Context) error {
+	s.metricsLock.Lock()
+	srv := s.metricsServer
+	s.metricsLock.Unlock()
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// openapiYAML describes every route of the relay, the v2 routes are derived from the paths marked with x-versioned.
+//
+//go:embed openapi.yaml
+var openapiYAML []byte
+
+// openapiJSON is the OpenAPI document served at /openapi.json.
+var openapiJSON = sync.OnceValues(openapiSpec)
+
+// OpenAPI serves the OpenAPI document of the relay.
+func (s *Server) OpenAPI(c echo.Context) error {
+	spec, err := openapiJSON()
+	if err != nil {
+		c.Logger().Error(err)
+		return c.NoContent(http.StatusInternalServerError)
+	}
+	return c.JSONBlob(http.StatusOK, spec)
+}
+
+// openapiSpec converts the embedded document to JSON, adding the v2 copy of the versioned paths.
+func openapiSpec() ([]byte, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(openapiYAML, &doc); err != nil {
+		return nil, fmt.Errorf("fail to decode openapi document, err: %w", err)
+	}
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("openapi document has no paths")
+	}
+	for path, item := range paths {
+		item, ok := item.(map[string]interface{})
+		if !ok || item["x-versioned"] != true {
+			continue
+		}
+		v2Item, err := v2PathItem(item)
+		if err != nil {
+			return nil, fmt.Errorf("fail to derive v2 path of %s, err: %w", path, err)
+		}
+		paths[v2Prefix+path] = v2Item
+	}
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("fail to encode openapi document, err: %w", err)
+	}
+	return buf, nil
+}
+
+// v2PathItem copies the path item of a relay route, its failures are answered with an ErrorResponse.
+func v2PathItem(item map[string]interface{}) (map[string]interface{}, error) {
+	// a JSON round trip deep copies the item
+	buf, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var v2Item map[string]interface{}
+	if err := json.Unmarshal(buf, &v2Item); err != nil {
+		return nil, err
+	}
+	delete(v2Item, "x-versioned")
+	errorResponse := map[string]interface{}{"$ref": "#/components/responses/Error"}
+	for _, method := range []string{"get", "post", "put", "delete"} {
+		op, ok := v2Item[method].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, ok := op["operationId"].(string); ok {
+			op["operationId"] = "v2" + strings.ToUpper(id[:1]) + id[1:]
+		}
+		responses, ok := op["responses"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for status := range responses {
+			if status >= "400" {
+				responses[status] = errorResponse
+			}
+		}
+		responses["default"] = errorResponse
+	}
+	return v2Item, nil
+}
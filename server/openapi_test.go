@@ -0,0 +1,148 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/labstack/echo/v4"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+var (
+	specOnce   sync.Once
+	specDoc    *openapi3.T
+	specRouter routers.Router
+	specErr    error
+)
+
+// loadSpec loads the document served at /openapi.json.
+func loadSpec(t *testing.T) (*openapi3.T, routers.Router) {
+	t.Helper()
+	specOnce.Do(func() {
+		// the binary encodings of the messages are checked by their media type only
+		openapi3filter.RegisterBodyDecoder(MIMEApplicationCBOR, openapi3filter.FileBodyDecoder)
+		openapi3filter.RegisterBodyDecoder(MIMEApplicationProtobuf, openapi3filter.FileBodyDecoder)
+		var buf []byte
+		if buf, specErr = openapiJSON(); specErr != nil {
+			return
+		}
+		loader := openapi3.NewLoader()
+		if specDoc, specErr = loader.LoadFromData(buf); specErr != nil {
+			return
+		}
+		if specErr = specDoc.Validate(loader.Context); specErr != nil {
+			return
+		}
+		specRouter, specErr = gorillamux.NewRouter(specDoc)
+	})
+	if specErr != nil {
+		t.Fatalf("fail to load openapi document, err: %v", specErr)
+	}
+	return specDoc, specRouter
+}
+
+// validating checks the requests and responses served by handler against the OpenAPI document.
+// A request the document rejects must be rejected by the relay with a 4xx status,
+// and every response must be documented for its operation.
+func validating(t *testing.T, handler http.Handler) http.Handler {
+	_, router := loadSpec(t)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := router.FindRoute(r)
+		if err != nil {
+			// unknown routes are covered by TestOpenAPIRoutes
+			handler.ServeHTTP(w, r)
+			return
+		}
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+				IncludeResponseStatus: true,
+			},
+		}
+		// the body is read by the validation, it is restored for the handler
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		requestErr := openapi3filter.ValidateRequest(context.Background(), input)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		operation := r.Method + " " + route.Path
+		if requestErr != nil && (rec.Code < 400 || rec.Code >= 500) {
+			t.Errorf("%s: the relay answered %d to a request the document rejects, err: %v", operation, rec.Code, requestErr)
+		}
+		responseBody, err := decodeContent(rec.Header().Get("Content-Encoding"), rec.Body.Bytes())
+		if err != nil {
+			t.Errorf("%s: fail to decode the %s response, err: %v", operation, rec.Header().Get("Content-Encoding"), err)
+		}
+		responseOptions := *input.Options
+		// empty bodies are sent without content type, e.g. an inbox that was never written, only the status is checked
+		responseOptions.ExcludeResponseBody = len(responseBody) == 0
+		err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 rec.Code,
+			Header:                 rec.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(responseBody)),
+			Options:                &responseOptions,
+		})
+		if err != nil {
+			t.Errorf("%s: response %d doesn't match the document, err: %v", operation, rec.Code, err)
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	})
+}
+
+// TestOpenAPIRoutes checks every route of the relay is documented, and every documented operation is served.
+func TestOpenAPIRoutes(t *testing.T) {
+	doc, _ := loadSpec(t)
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	cfg := config.DefaultConfig()
+	cfg.Admin.Token = "0123456789abcdef0123"
+	s := NewServer(&cfg, store)
+	s.setupRoutes()
+
+	param := regexp.MustCompile(`:(\w+)`)
+	var served []string
+	for _, route := range s.e.Routes() {
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		served = append(served, route.Method+" "+param.ReplaceAllString(route.Path, "{$1}"))
+	}
+	var documented []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented = append(documented, method+" "+path)
+		}
+	}
+	sort.Strings(served)
+	sort.Strings(documented)
+	if strings.Join(served, "\n") != strings.Join(documented, "\n") {
+		t.Fatalf("routes and openapi document differ\nserved:\n%s\n\ndocumented:\n%s", strings.Join(served, "\n"), strings.Join(documented, "\n"))
+	}
+}
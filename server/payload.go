@@ -0,0 +1,316 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vultisig/vultisig-relay/contexthelper"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// Upload is the state of a chunked payload upload. Chunks are sent in order, each one starting at the offset of
+// the upload, so a client that lost its connection asks for the offset and resumes from it.
+type Upload struct {
+	ID string `json:"upload_id"`
+	// Hash is the ID of the payload, a SHA-256 or algorithm:hex
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+	// Offset is the number of bytes received, the next chunk starts at it
+	Offset int64 `json:"offset"`
+	// ExpiresAt is shared by the chunks: each one is stored for storage.expiration.value, and is written before
+	// the upload expires, so all of them are kept until then
+	ExpiresAt time.Time `json:"expires_at"`
+	Completed bool      `json:"completed"`
+	// SessionIDs are the sessions referencing the payload once it is completed
+	SessionIDs []string `json:"session_ids,omitempty"`
+}
+
+// uploadState is the stored state of an upload, with the chunks received so far.
+type uploadState struct {
+	Upload
+	Chunks []storage.PayloadChunk `json:"chunks"`
+}
+
+// payloadSessions returns the session IDs a payload is stored for, without empty or duplicate IDs.
+func payloadSessions(sessionIDs []string) []string {
+	var sessions []string
+	for _, id := range sessionIDs {
+		id = strings.TrimSpace(id)
+		if id != "" && !slices.Contains(sessions, id) {
+			sessions = append(sessions, id)
+		}
+	}
+	return sessions
+}
+
+// CreatePayloadUpload starts the chunked upload of a payload of the announced size.
+func (s *Server) CreatePayloadUpload(c echo.Context) error {
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
+	}
+	payloadID, err := storage.ParsePayloadID(c.Param("hash"))
+	if err != nil {
+		return badRequest(CodeInvalidHash, "invalid payload ID", err)
+	}
+	var req struct {
+		Size       int64    `json:"size"`
+		SessionIDs []string `json:"session_ids"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return badRequest(CodeInvalidBody, "upload must be a JSON object", err)
+	}
+	if req.Size <= 0 || req.Size > s.payload.MaxSize {
+		return badRequest(CodeInvalidBody, fmt.Sprintf("size must be between 1 and %d bytes", s.payload.MaxSize), nil)
+	}
+	id, err := storage.NewPayloadID()
+	if err != nil {
+		return &apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "fail to generate upload ID", err: err}
+	}
+	upload := &uploadState{Upload: Upload{
+		ID:         id,
+		Hash:       payloadID.String(),
+		Size:       req.Size,
+		ExpiresAt:  time.Now().Add(s.valueTTL).UTC().Truncate(time.Second),
+		SessionIDs: payloadSessions(req.SessionIDs),
+	}}
+	if err := s.saveUpload(c, upload); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, upload.Upload)
+}
+
+// GetPayloadUpload returns the state of an upload, its offset is where the upload resumes.
+func (s *Server) GetPayloadUpload(c echo.Context) error {
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
+	}
+	upload, err := s.loadUpload(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, upload.Upload)
+}
+
+// PutPayloadChunk stores the next chunk of an upload, the offset query parameter must be the offset of the upload.
+// The chunk is stored as it is read, in chunks of storage.PayloadChunkSize.
+func (s *Server) PutPayloadChunk(c echo.Context) error {
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
+	}
+	upload, err := s.loadUpload(c)
+	if err != nil {
+		return err
+	}
+	offset, err := strconv.ParseInt(c.QueryParam("offset"), 10, 64)
+	if err != nil {
+		return badRequest(CodeInvalidBody, "offset must be a number", err)
+	}
+	if offset != upload.Offset {
+		return &apiError{status: http.StatusConflict, code: CodeOffsetMismatch, message: fmt.Sprintf("upload is at offset %d", upload.Offset)}
+	}
+	remaining := upload.Size - upload.Offset
+	// the chunks stored past the state of the upload, by a failed request, are replaced by the next request
+	w := storage.NewPayloadWriter(c.Request().Context(), s.s, storage.PayloadManifest{ID: upload.ID, Size: upload.Offset, Chunks: upload.Chunks})
+	n, err := io.Copy(w, io.LimitReader(c.Request().Body, remaining+1))
+	if err != nil {
+		if w.Err() != nil {
+			return storageError("fail to store chunk", err)
+		}
+		return badRequest(CodeInvalidBody, "fail to read body", err)
+	}
+	if n == 0 {
+		return badRequest(CodeInvalidBody, "chunk is empty", nil)
+	}
+	if n > remaining {
+		return badRequest(CodeInvalidBody, fmt.Sprintf("chunk exceeds the payload size, %d bytes remaining", remaining), nil)
+	}
+	if err := w.Flush(); err != nil {
+		return storageError("fail to store chunk", err)
+	}
+	manifest := w.Manifest()
+	upload.Offset, upload.Chunks = manifest.Size, manifest.Chunks
+	if err := s.saveUpload(c, upload); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, upload.Upload)
+}
+
+// CompletePayloadUpload verifies the received chunks against the payload ID and stores them as the payload.
+// Completing a completed upload succeeds, so the request can be retried.
+func (s *Server) CompletePayloadUpload(c echo.Context) error {
+	if err := contexthelper.CheckCancellation(c.Request().Context()); err != nil {
+		return cancelled(err)
+	}
+	upload, err := s.loadUpload(c)
+	if err != nil {
+		return err
+	}
+	if upload.Completed {
+		return c.JSON(http.StatusOK, upload.Upload)
+	}
+	if upload.Offset != upload.Size {
+		return &apiError{status: http.StatusConflict, code: CodeUploadIncomplete, message: fmt.Sprintf("upload has %d of %d bytes", upload.Offset, upload.Size)}
+	}
+	manifest := storage.PayloadManifest{ID: upload.ID, Size: upload.Size, Chunks: upload.Chunks}
+	payloadID, err := storage.ParsePayloadID(upload.Hash)
+	if err != nil {
+		return &apiError{status: http.StatusInternalServerError, code: CodeCorruptValue, message: "stored upload is invalid", err: err}
+	}
+	if err := storage.CommitPayload(c.Request().Context(), s.s, payloadID, manifest, upload.SessionIDs); err != nil {
+		if errors.Is(err, storage.ErrCorruptPayload) {
+			return badRequest(CodeHashMismatch, "chunks do not match the hash, the upload has to start over", err)
+		}
+		return lookupError(CodeNotFound, "fail to store payload", err)
+	}
+	upload.Completed = true
+	if err := s.saveUpload(c, upload); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, upload.Upload)
+}
+
+// loadUpload returns the upload of the path, an expired upload or an upload of another payload is not found.
+func (s *Server) loadUpload(c echo.Context) (*uploadState, error) {
+	uploadID := strings.TrimSpace(c.Param("uploadID"))
+	value, err := s.s.GetValue(c.Request().Context(), storage.UploadKey(uploadID))
+	if err != nil {
+		return nil, lookupError(CodeNotFound, "upload not found", err)
+	}
+	var upload uploadState
+	if err := json.Unmarshal([]byte(value), &upload); err != nil {
+		return nil, &apiError{status: http.StatusInternalServerError, code: CodeCorruptValue, message: "stored upload is invalid", err: err}
+	}
+	if payloadID, err := storage.ParsePayloadID(c.Param("hash")); err != nil || upload.Hash != payloadID.String() {
+		return nil, notFound(CodeNotFound, "upload not found")
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, notFound(CodeNotFound, "upload expired")
+	}
+	return &upload, nil
+}
+
+func (s *Server) saveUpload(c echo.Context, upload *uploadState) error {
+	buf, err := json.Marshal(upload)
+	if err != nil {
+		return &apiError{status: http.StatusInternalServerError, code: CodeInternal, message: "fail to encode upload", err: err}
+	}
+	if err := s.s.SetValue(c.Request().Context(), storage.UploadKey(upload.ID), string(buf)); err != nil {
+		return storageError("fail to store upload", err)
+	}
+	return nil
+}
+
+// writePayload streams the payload, or the part of it requested by a single range Range header, one chunk at a time.
+// A Range header with several ranges, or another unit than bytes, is answered with the whole payload.
+// The ID of the payload is its ETag, it never changes.
+func writePayload(c echo.Context, payloadID string, payload *storage.Payload) error {
+	header := c.Response().Header()
+	etag := `"` + payloadID + `"`
+	header.Set("ETag", etag)
+	header.Set("Accept-Ranges", "bytes")
+	if matchETag(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	size := payload.Size()
+	start, end, ok := parseRange(c.Request().Header.Get("Range"), size)
+	if !ok {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return &apiError{status: http.StatusRequestedRangeNotSatisfiable, code: CodeInvalidRange, message: fmt.Sprintf("range is not within the %d bytes of the payload", size)}
+	}
+	r, err := payload.NewReader(c.Request().Context(), start, end)
+	if err != nil {
+		return payloadError(err)
+	}
+	status := http.StatusOK
+	if start != 0 || end != size {
+		status = http.StatusPartialContent
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+	}
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(end-start, 10))
+	// a chunk that fails once the response started aborts it, the client gets less than Content-Length
+	return c.Stream(status, echo.MIMETextPlainCharsetUTF8, r)
+}
+
+// checkPayloadScope returns storage.ErrNotFound unless the payload can be read with the session: a payload stored
+// only for sessions is read with one of them that wasn't deleted or expired. A payload out of scope is not found, so
+// a client that only knows its ID learns nothing about it.
+func checkPayloadScope(ctx context.Context, s storage.Storage, payloadID storage.PayloadID, sessionID string) error {
+	sessions, err := storage.PayloadScope(ctx, s, payloadID)
+	if err != nil || len(sessions) == 0 {
+		return err
+	}
+	if !slices.Contains(sessions, sessionID) {
+		return storage.ErrNotFound
+	}
+	participants, err := s.GetSession(ctx, storage.SessionKey(sessionID))
+	if err != nil {
+		return err
+	}
+	if len(participants) == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// payloadError returns the error of a payload that can't be read.
+func payloadError(err error) error {
+	if errors.Is(err, storage.ErrCorruptPayload) {
+		return &apiError{status: http.StatusInternalServerError, code: CodeCorruptValue, message: "stored payload does not match its hash", err: err}
+	}
+	return lookupError(CodeNotFound, "payload not found", err)
+}
+
+// matchETag returns true when the If-None-Match header lists etag, or is *.
+func matchETag(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange returns the bytes [start, end) of a payload of size requested by a Range header,
+// the whole payload when the header doesn't request a single byte range, and false when the range is unsatisfiable.
+func parseRange(rangeHeader string, size int64) (int64, int64, bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(rangeHeader), "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, size, true
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+	if first == "" {
+		// a suffix range, the last bytes of the payload
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		return max(size-n, 0), size, true
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end := size
+	if last != "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < start {
+			return 0, 0, false
+		}
+		end = min(n+1, size)
+	}
+	return start, end, true
+}
@@ -0,0 +1,342 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// serve sends a request to handler and returns the status, the header and the body of the response.
+func serve(handler http.Handler, method, path string, header http.Header, body string) (int, http.Header, string) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Header(), rec.Body.String()
+}
+
+func TestPayloadUpload(t *testing.T) {
+	for _, prefix := range []string{"", v2Prefix} {
+		t.Run("prefix "+prefix, func(t *testing.T) {
+			_, handler := newTestServer(t, nil)
+			payload := strings.Repeat("keysign payload ", 1000)
+			hash := hashOf(payload)
+			jsonHeader := http.Header{"Content-Type": {"application/json"}}
+			status, _, body := serve(handler, http.MethodPost, prefix+"/payload/"+hash+"/upload", jsonHeader, fmt.Sprintf(`{"size":%d}`, len(payload)))
+			if status != http.StatusCreated {
+				t.Fatalf("fail to create upload, status: %d %s", status, body)
+			}
+			var upload Upload
+			if err := json.Unmarshal([]byte(body), &upload); err != nil {
+				t.Fatal(err)
+			}
+			uploadPath := prefix + "/payload/" + hash + "/upload/" + upload.ID
+			put := func(offset int, chunk string) (int, string) {
+				status, _, body := serve(handler, http.MethodPut, fmt.Sprintf("%s?offset=%d", uploadPath, offset), nil, chunk)
+				return status, body
+			}
+
+			if status, _, body := serve(handler, http.MethodPost, uploadPath, nil, ""); status != http.StatusConflict {
+				t.Fatalf("expected an incomplete upload to fail, status: %d %s", status, body)
+			}
+			if status, body := put(0, payload[:6000]); status != http.StatusOK {
+				t.Fatalf("fail to put chunk, status: %d %s", status, body)
+			}
+			// the chunk is sent again, as when its response was lost
+			if status, body := put(0, payload[:6000]); status != http.StatusConflict {
+				t.Fatalf("expected a chunk at a wrong offset to fail, status: %d %s", status, body)
+			}
+			// the client resumes from the offset of the upload
+			status, _, body = serve(handler, http.MethodGet, uploadPath, nil, "")
+			if err := json.Unmarshal([]byte(body), &upload); status != http.StatusOK || err != nil {
+				t.Fatalf("fail to get upload, status: %d %s", status, body)
+			}
+			if upload.Offset != 6000 {
+				t.Fatalf("expected offset 6000, got %+v", upload)
+			}
+			if status, body := put(6000, payload[6000:]+"extra"); status != http.StatusBadRequest {
+				t.Fatalf("expected a chunk exceeding the size to fail, status: %d %s", status, body)
+			}
+			if status, body := put(6000, payload[6000:]); status != http.StatusOK {
+				t.Fatalf("fail to put chunk, status: %d %s", status, body)
+			}
+			for i := 0; i < 2; i++ {
+				if status, _, body := serve(handler, http.MethodPost, uploadPath, nil, ""); status != http.StatusOK {
+					t.Fatalf("fail to complete upload, status: %d %s", status, body)
+				}
+			}
+			if status, _, body := serve(handler, http.MethodGet, prefix+"/payload/"+hash, nil, ""); status != http.StatusOK || body != payload {
+				t.Fatalf("expected the uploaded payload, status: %d, got %d bytes", status, len(body))
+			}
+
+			if status, _, body := serve(handler, http.MethodGet, prefix+"/payload/"+hashOf("other")+"/upload/"+upload.ID, nil, ""); status != http.StatusNotFound {
+				t.Fatalf("expected the upload of another payload to be not found, status: %d %s", status, body)
+			}
+			if status, _, body := serve(handler, http.MethodPost, prefix+"/payload/"+hash+"/upload", jsonHeader, `{"size":0}`); status != http.StatusBadRequest {
+				t.Fatalf("expected an empty upload to fail, status: %d %s", status, body)
+			}
+		})
+	}
+}
+
+func TestPayloadUploadHashMismatch(t *testing.T) {
+	_, handler := newTestServer(t, nil)
+	hash := hashOf("payload")
+	status, _, body := serve(handler, http.MethodPost, v2Prefix+"/payload/"+hash+"/upload", http.Header{"Content-Type": {"application/json"}}, `{"size":7}`)
+	if status != http.StatusCreated {
+		t.Fatalf("fail to create upload, status: %d %s", status, body)
+	}
+	var upload Upload
+	if err := json.Unmarshal([]byte(body), &upload); err != nil {
+		t.Fatal(err)
+	}
+	uploadPath := v2Prefix + "/payload/" + hash + "/upload/" + upload.ID
+	if status, _, body := serve(handler, http.MethodPut, uploadPath+"?offset=0", nil, "PAYLOAD"); status != http.StatusOK {
+		t.Fatalf("fail to put chunk, status: %d %s", status, body)
+	}
+	status, _, body = serve(handler, http.MethodPost, uploadPath, nil, "")
+	if status != http.StatusBadRequest || !strings.Contains(body, CodeHashMismatch) {
+		t.Fatalf("expected a hash mismatch, status: %d %s", status, body)
+	}
+	if status, _, _ := serve(handler, http.MethodGet, v2Prefix+"/payload/"+hash, nil, ""); status != http.StatusNotFound {
+		t.Fatalf("expected no payload, status: %d", status)
+	}
+}
+
+func TestPayloadRange(t *testing.T) {
+	_, handler := newTestServer(t, nil)
+	payload := "0123456789"
+	hash := hashOf(payload)
+	if status, _, body := serve(handler, http.MethodPost, "/payload/"+hash, nil, payload); status != http.StatusOK {
+		t.Fatalf("fail to store payload, status: %d %s", status, body)
+	}
+	tests := []struct {
+		rangeHeader      string
+		wantStatus       int
+		wantBody         string
+		wantContentRange string
+	}{
+		{rangeHeader: "", wantStatus: http.StatusOK, wantBody: payload},
+		{rangeHeader: "bytes=2-4", wantStatus: http.StatusPartialContent, wantBody: "234", wantContentRange: "bytes 2-4/10"},
+		{rangeHeader: "bytes=7-", wantStatus: http.StatusPartialContent, wantBody: "789", wantContentRange: "bytes 7-9/10"},
+		{rangeHeader: "bytes=-2", wantStatus: http.StatusPartialContent, wantBody: "89", wantContentRange: "bytes 8-9/10"},
+		{rangeHeader: "bytes=5-100", wantStatus: http.StatusPartialContent, wantBody: "56789", wantContentRange: "bytes 5-9/10"},
+		{rangeHeader: "bytes=0-1,4-5", wantStatus: http.StatusOK, wantBody: payload},
+		{rangeHeader: "bytes=10-", wantStatus: http.StatusRequestedRangeNotSatisfiable, wantContentRange: "bytes */10"},
+		{rangeHeader: "bytes=4-2", wantStatus: http.StatusRequestedRangeNotSatisfiable, wantContentRange: "bytes */10"},
+	}
+	for _, tt := range tests {
+		reqHeader := http.Header{}
+		if tt.rangeHeader != "" {
+			reqHeader.Set("Range", tt.rangeHeader)
+		}
+		for _, prefix := range []string{"", v2Prefix} {
+			status, header, body := serve(handler, http.MethodGet, prefix+"/payload/"+hash, reqHeader, "")
+			if status != tt.wantStatus || header.Get("Content-Range") != tt.wantContentRange {
+				t.Errorf("%s range %q: expected %d %q, got %d %q", prefix, tt.rangeHeader, tt.wantStatus, tt.wantContentRange, status, header.Get("Content-Range"))
+			}
+			if tt.wantBody != "" && body != tt.wantBody {
+				t.Errorf("%s range %q: expected %q, got %q", prefix, tt.rangeHeader, tt.wantBody, body)
+			}
+		}
+	}
+}
+
+func TestPayloadStreaming(t *testing.T) {
+	_, handler := newTestServer(t, nil)
+	// the payload spans several chunks
+	payload := strings.Repeat("keysign payload ", 200000)
+	hash := hashOf(payload)
+	if status, _, body := serve(handler, http.MethodPost, "/payload/"+hash, nil, payload); status != http.StatusOK {
+		t.Fatalf("fail to store payload, status: %d %s", status, body)
+	}
+	status, header, body := serve(handler, http.MethodGet, "/payload/"+hash, nil, "")
+	if status != http.StatusOK || body != payload {
+		t.Fatalf("expected the payload, status: %d, got %d bytes", status, len(body))
+	}
+	etag := header.Get("ETag")
+	if etag != `"`+hash+`"` {
+		t.Fatalf("expected the hash as ETag, got %s", etag)
+	}
+	for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "*"} {
+		if status, _, body := serve(handler, http.MethodGet, v2Prefix+"/payload/"+hash, http.Header{"If-None-Match": {ifNoneMatch}}, ""); status != http.StatusNotModified || body != "" {
+			t.Errorf("If-None-Match %s: expected 304, got %d with %d bytes", ifNoneMatch, status, len(body))
+		}
+	}
+	if status, _, _ := serve(handler, http.MethodGet, "/payload/"+hash, http.Header{"If-None-Match": {`"other"`}}, ""); status != http.StatusOK {
+		t.Errorf("expected the payload for another ETag, got %d", status)
+	}
+	status, _, body = serve(handler, http.MethodGet, "/payload/"+hash, http.Header{"Range": {"bytes=1048570-1048589"}}, "")
+	if status != http.StatusPartialContent || body != payload[1048570:1048590] {
+		t.Errorf("expected a range across chunks, status: %d, got %q", status, body)
+	}
+}
+
+func TestPayloadMaxSize(t *testing.T) {
+	store, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	cfg := config.DefaultConfig()
+	cfg.Payload.MaxSize = 8
+	s := NewServer(&cfg, store)
+	handler := validating(t, s.Handler())
+	s.e.Logger.SetOutput(io.Discard)
+
+	if status, _, body := serve(handler, http.MethodPost, "/payload/"+hashOf("12345678"), nil, "12345678"); status != http.StatusOK {
+		t.Fatalf("fail to store payload, status: %d %s", status, body)
+	}
+	status, _, body := serve(handler, http.MethodPost, v2Prefix+"/payload/"+hashOf("123456789"), nil, "123456789")
+	if status != http.StatusRequestEntityTooLarge || !strings.Contains(body, CodeRequestTooLarge) {
+		t.Fatalf("expected a payload too large, status: %d %s", status, body)
+	}
+	// without Content-Length the limit is enforced while the body is read
+	req := httptest.NewRequest(http.MethodPost, "/payload/"+hashOf("123456789"), io.MultiReader(strings.NewReader("1234"), strings.NewReader("56789")))
+	req.ContentLength = -1
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected a payload too large, status: %d", rec.Code)
+	}
+	status, _, body = serve(handler, http.MethodPost, "/payload/"+hashOf("123456789")+"/upload", http.Header{"Content-Type": {"application/json"}}, `{"size":9}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected an upload too large to fail, status: %d %s", status, body)
+	}
+}
+
+// payloadID returns the ID of payload with the algorithm.
+func payloadID(algorithm, payload string) string {
+	h := storage.PayloadID{Algorithm: algorithm}.NewHash()
+	_, _ = io.WriteString(h, payload)
+	return storage.PayloadID{Algorithm: algorithm, Digest: hex.EncodeToString(h.Sum(nil))}.String()
+}
+
+func TestPayloadSessions(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	for _, sessionID := range []string{"s1", "s2"} {
+		if status, _, body := serve(handler, http.MethodPost, "/"+sessionID, http.Header{"Content-Type": {"application/json"}}, `["a"]`); status != http.StatusCreated {
+			t.Fatalf("fail to create session, status: %d %s", status, body)
+		}
+	}
+	payload := "keysign payload"
+	blake := payloadID(storage.HashBLAKE3, payload)
+	if status, _, body := serve(handler, http.MethodPost, "/payload/"+blake+"?session_id=s1", nil, payload); status != http.StatusOK {
+		t.Fatalf("fail to store payload, status: %d %s", status, body)
+	}
+	// the same content is uploaded in chunks with its SHA-256 for another session
+	status, _, body := serve(handler, http.MethodPost, v2Prefix+"/payload/"+hashOf(payload)+"/upload", http.Header{"Content-Type": {"application/json"}}, fmt.Sprintf(`{"size":%d,"session_ids":["s2"]}`, len(payload)))
+	var upload Upload
+	if err := json.Unmarshal([]byte(body), &upload); status != http.StatusCreated || err != nil {
+		t.Fatalf("fail to create upload, status: %d %s", status, body)
+	}
+	uploadPath := v2Prefix + "/payload/" + hashOf(payload) + "/upload/" + upload.ID
+	if status, _, body := serve(handler, http.MethodPut, uploadPath+"?offset=0", nil, payload); status != http.StatusOK {
+		t.Fatalf("fail to put chunk, status: %d %s", status, body)
+	}
+	if status, _, body := serve(handler, http.MethodPost, uploadPath, nil, ""); status != http.StatusOK {
+		t.Fatalf("fail to complete upload, status: %d %s", status, body)
+	}
+	for _, id := range []string{blake, hashOf(payload), "sha2-256:" + hashOf(payload)} {
+		status, header, body := serve(handler, http.MethodGet, v2Prefix+"/payload/"+id+"?session_id=s1", nil, "")
+		if status != http.StatusOK || body != payload {
+			t.Fatalf("%s: expected the payload, status: %d %s", id, status, body)
+		}
+		if etag := header.Get("ETag"); id == blake && etag != `"`+blake+`"` {
+			t.Errorf("expected the ID as ETag, got %s", etag)
+		}
+	}
+
+	if status, _, _ := serve(handler, http.MethodDelete, "/s1", nil, ""); status != http.StatusOK {
+		t.Fatalf("fail to delete session, status: %d", status)
+	}
+	if status, _, _ := serve(handler, http.MethodGet, "/payload/"+blake+"?session_id=s2", nil, ""); status != http.StatusOK {
+		t.Fatalf("expected the payload to be kept for s2, status: %d", status)
+	}
+	if status, _, _ := serve(handler, http.MethodDelete, "/s2", nil, ""); status != http.StatusOK {
+		t.Fatalf("fail to delete session, status: %d", status)
+	}
+	for _, id := range []string{blake, hashOf(payload)} {
+		if status, _, _ := serve(handler, http.MethodGet, "/payload/"+id+"?session_id=s2", nil, ""); status != http.StatusNotFound {
+			t.Fatalf("%s: expected the payload to be freed, status: %d", id, status)
+		}
+	}
+
+	// the mismatch is found once the body is read, after its first chunk was stored
+	status, _, body = serve(handler, http.MethodPost, v2Prefix+"/payload/"+blake, nil, strings.Repeat("other ", storage.PayloadChunkSize/4))
+	if status != http.StatusBadRequest || !strings.Contains(body, CodeHashMismatch) {
+		t.Fatalf("expected a blake3 mismatch, status: %d %s", status, body)
+	}
+	if n := countChunks(t, s); n != 0 {
+		t.Fatalf("expected the chunks of a rejected payload to be deleted, got %d", n)
+	}
+}
+
+func TestPayloadScope(t *testing.T) {
+	s, handler := newTestServer(t, nil)
+	if status, _, body := serve(handler, http.MethodPost, "/s1", http.Header{"Content-Type": {"application/json"}}, `["a","b"]`); status != http.StatusCreated {
+		t.Fatalf("fail to create session, status: %d %s", status, body)
+	}
+	payload := "scoped keysign payload"
+	hash := hashOf(payload)
+	if status, _, body := serve(handler, http.MethodPost, "/payload/"+hash+"?session_id=s1", nil, payload); status != http.StatusOK {
+		t.Fatalf("fail to store payload, status: %d %s", status, body)
+	}
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{name: "session", query: "?session_id=s1", wantStatus: http.StatusOK},
+		{name: "no session", wantStatus: http.StatusNotFound},
+		{name: "other session", query: "?session_id=s2", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		for _, prefix := range []string{"", v2Prefix} {
+			status, _, body := serve(handler, http.MethodGet, prefix+"/payload/"+hash+tt.query, nil, "")
+			if status != tt.wantStatus {
+				t.Errorf("%s %s: expected %d, got %d %s", prefix, tt.name, tt.wantStatus, status, body)
+			}
+			if status == http.StatusOK && body != payload {
+				t.Errorf("%s %s: expected the payload, got %q", prefix, tt.name, body)
+			}
+		}
+	}
+
+	// a payload stored without a session is read by anyone, whatever else referenced its content
+	public := "public keysign payload"
+	if status, _, body := serve(handler, http.MethodPost, "/payload/"+hashOf(public)+"?session_id=s1", nil, public); status != http.StatusOK {
+		t.Fatalf("fail to store payload, status: %d %s", status, body)
+	}
+	if status, _, body := serve(handler, http.MethodPost, "/payload/"+hashOf(public), nil, public); status != http.StatusOK {
+		t.Fatalf("fail to store payload, status: %d %s", status, body)
+	}
+	if status, _, _ := serve(handler, http.MethodGet, "/payload/"+hashOf(public), nil, ""); status != http.StatusOK {
+		t.Fatalf("expected a payload stored without a session to be read, status: %d", status)
+	}
+	// the session expires, its payloads are kept until they expire too but can't be read with it
+	if err := s.s.DeleteValue(context.Background(), storage.SessionKey("s1")); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, _ := serve(handler, http.MethodGet, "/payload/"+hash+"?session_id=s1", nil, ""); status != http.StatusNotFound {
+		t.Fatalf("expected a payload of an expired session to be not found, status: %d", status)
+	}
+	// a scoped payload whose readers are lost is not found instead of readable by anyone
+	if err := s.s.DeleteValue(context.Background(), storage.PayloadReadersKey(hash)); err != nil {
+		t.Fatal(err)
+	}
+	if status, _, _ := serve(handler, http.MethodGet, "/payload/"+hash, nil, ""); status != http.StatusNotFound {
+		t.Fatalf("expected a payload without readers to be not found, status: %d", status)
+	}
+}
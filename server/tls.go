@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/vultisig/vultisig-relay/config"
+)
+
+// certReloadInterval is how often the certificate files are checked for changes.
+const certReloadInterval = time.Second * 30
+
+// certReloader serves the TLS certificate and client CAs from files, and reloads them when the files change.
+type certReloader struct {
+	cfg        config.TLS
+	minVersion uint16
+
+	lock     sync.RWMutex
+	current  *tls.Config
+	modTimes map[string]time.Time
+}
+
+func newCertReloader(cfg config.TLS) (*certReloader, error) {
+	minVersion, err := config.ParseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	r := &certReloader{
+		cfg:        cfg,
+		minVersion: minVersion,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files returns the files the TLS config is loaded from.
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return fmt.Errorf("fail to stat %s, err: %w", f, err)
+		}
+		modTimes[f] = info.ModTime()
+	}
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("fail to load certificate, err: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   r.minVersion,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if r.cfg.ClientCAFile != "" {
+		buf, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("fail to read client ca file %s, err: %w", r.cfg.ClientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return fmt.Errorf("no certificate found in client ca file %s", r.cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if r.cfg.OptionalClientCert {
+			// the admin API still insists on a certificate of its client identities
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.current = tlsConfig
+	r.modTimes = modTimes
+	return nil
+}
+
+// changed returns true when any of the files has been modified since the last reload.
+func (r *certReloader) changed() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for f, modTime := range r.modTimes {
+		info, err := os.Stat(f)
+		if err != nil {
+			// the file may be in the middle of being replaced, check again later
+			continue
+		}
+		if !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// watch reloads the TLS config whenever the files change, until the context is cancelled.
+// If the new files are invalid, the previous certificate keeps being served.
+func (r *certReloader) watch(ctx context.Context, logger echo.Logger) {
+	ticker := time.NewTicker(certReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				logger.Errorf("fail to reload tls certificate, err: %s", err)
+				continue
+			}
+			logger.Info("tls certificate reloaded")
+		}
+	}
+}
+
+// TLSConfig returns a config that always uses the latest loaded certificate.
+func (r *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.lock.RLock()
+			defer r.lock.RUnlock()
+			return r.current, nil
+		},
+	}
+}
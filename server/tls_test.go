@@ -0,0 +1,241 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vultisig/vultisig-relay/config"
+)
+
+// testCert is a certificate with its key, signed by parent or self-signed when parent is nil.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write stores the certificate and its key as PEM files in dir, and returns their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// handshake connects a client to the TLS config of the reloader, and returns the certificate the server presented.
+func handshake(t *testing.T, r *certReloader, client *tls.Config) (*x509.Certificate, error) {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", r.TLSConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// the client only learns that its certificate was refused once it reads
+		if err := conn.(*tls.Conn).Handshake(); err == nil {
+			_, _ = conn.Write([]byte{1})
+		}
+	}()
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.Read(make([]byte, 1)); err != nil {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "localhost", ca).write(t, dir, "server")
+	client := newTestCert(t, "operator", ca)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name       string
+		optional   bool
+		clientCert bool
+		wantErr    bool
+	}{
+		{name: "required with certificate", clientCert: true},
+		{name: "required without certificate", wantErr: true},
+		{name: "optional with certificate", optional: true, clientCert: true},
+		{name: "optional without certificate", optional: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newCertReloader(config.TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, OptionalClientCert: tt.optional})
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientConfig := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+			if tt.clientCert {
+				clientConfig.Certificates = []tls.Certificate{client.tlsCertificate()}
+			}
+			_, err = handshake(t, r, clientConfig)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// a certificate that is given is verified in both modes
+	for _, optional := range []bool{false, true} {
+		r, err := newCertReloader(config.TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, OptionalClientCert: optional})
+		if err != nil {
+			t.Fatal(err)
+		}
+		untrusted := newTestCert(t, "operator", newTestCert(t, "other ca", nil)).tlsCertificate()
+		clientConfig := &tls.Config{
+			RootCAs:    roots,
+			ServerName: "localhost",
+			// Certificates would only be sent when signed by a CA the server asks for
+			GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return &untrusted, nil },
+		}
+		if _, err := handshake(t, r, clientConfig); err == nil {
+			t.Fatalf("optional %v: expected an untrusted client certificate to be refused", optional)
+		}
+	}
+}
+
+func TestCertRotation(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	first := newTestCert(t, "localhost", ca)
+	certFile, keyFile := first.write(t, dir, "server")
+	r, err := newCertReloader(config.TLS{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectServed := func(expected *testCert) {
+		t.Helper()
+		cert, err := handshake(t, r, client)
+		if err != nil {
+			t.Fatalf("fail to handshake, err: %v", err)
+		}
+		if cert.SerialNumber.Cmp(expected.cert.SerialNumber) != 0 {
+			t.Fatalf("expected certificate %s, got %s", expected.cert.SerialNumber, cert.SerialNumber)
+		}
+	}
+	// rotate writes the files again with a later modification time, as the watcher compares them
+	modTime := time.Now()
+	rotate := func(c *testCert, files ...string) {
+		t.Helper()
+		rotatedCert, rotatedKey := c.write(t, t.TempDir(), "server")
+		modTime = modTime.Add(time.Second)
+		for src, dst := range map[string]string{rotatedCert: certFile, rotatedKey: keyFile} {
+			if !slices.Contains(files, dst) {
+				continue
+			}
+			buf, err := os.ReadFile(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dst, buf, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(dst, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !r.changed() {
+			t.Fatal("expected the rotation to be noticed")
+		}
+	}
+	expectServed(first)
+
+	second := newTestCert(t, "localhost", ca)
+	rotate(second, certFile, keyFile)
+	if err := r.reload(); err != nil {
+		t.Fatalf("fail to reload, err: %v", err)
+	}
+	expectServed(second)
+
+	// a certificate without its key is refused, the previous one keeps being served
+	rotate(newTestCert(t, "localhost", ca), certFile)
+	if err := r.reload(); err == nil {
+		t.Fatal("expected a certificate that doesn't match its key to be refused")
+	}
+	expectServed(second)
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reload(); err == nil {
+		t.Fatal("expected an invalid certificate to be refused")
+	}
+	expectServed(second)
+}
@@ -0,0 +1,709 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/contexthelper"
+	"github.com/vultisig/vultisig-relay/model"
+)
+
+var _ Storage = (*BoltStorage)(nil)
+
+var (
+	// boltDataBucket holds every record by key
+	boltDataBucket = []byte("data")
+	// boltExpiryBucket indexes the records by expiration time, for the sweeper
+	boltExpiryBucket = []byte("expiry")
+	// boltUsersBucket holds the users by ID, they never expire
+	boltUsersBucket = []byte("users")
+)
+
+// boltSweepInterval is how often expired records are removed from the database.
+const boltSweepInterval = time.Minute
+
+// record kinds, an index record removes the keys it tracks when it expires
+const (
+	boltKindList     = "list"
+	boltKindMessages = "messages"
+	boltKindValue    = "value"
+	boltKindIndex    = "index"
+	boltKindSet      = "set"
+)
+
+// boltRecord is the value stored in the data bucket.
+type boltRecord struct {
+	Kind      string          `json:"kind"`
+	ExpiresAt int64           `json:"expires_at"`
+	List      []string        `json:"list,omitempty"`
+	Messages  []model.Message `json:"messages,omitempty"`
+	Value     []byte          `json:"value,omitempty"`
+}
+
+// BoltStorage is a storage backed by an embedded bbolt database file, so the relay can run
+// as a single binary without external services. Every write is a fsynced transaction.
+type BoltStorage struct {
+	db                *bolt.DB
+	defaultExpiration time.Duration
+	defaultUserExpire time.Duration
+	stop              chan struct{}
+	closeOnce         sync.Once
+	closeErr          error
+	wg                sync.WaitGroup
+}
+
+// NewBoltStorage opens or creates the database file and starts sweeping expired records.
+func NewBoltStorage(cfg config.Storage) (*BoltStorage, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("storage path is required for bolt storage")
+	}
+	db, err := bolt.Open(cfg.Path, 0600, &bolt.Options{Timeout: time.Second * 5})
+	if err != nil {
+		return nil, fmt.Errorf("fail to open bolt database %s, err: %w", cfg.Path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltDataBucket, boltExpiryBucket, boltUsersBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("fail to create buckets, err: %w", err)
+	}
+	s := &BoltStorage{
+		db:                db,
+		defaultExpiration: cfg.Expiration.SessionTTL(),
+		defaultUserExpire: cfg.Expiration.ValueTTL(),
+		stop:              make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.sweepLoop()
+	return s, nil
+}
+
+// expiryKey is the key of a record in the expiry bucket, ordered by expiration time.
+func expiryKey(expiresAt int64, key string) []byte {
+	buf := make([]byte, 8+len(key))
+	binary.BigEndian.PutUint64(buf, uint64(expiresAt))
+	copy(buf[8:], key)
+	return buf
+}
+
+// get returns the record stored under key, or nil when it doesn't exist or has expired.
+func (s *BoltStorage) get(tx *bolt.Tx, key string) (*boltRecord, error) {
+	buf := tx.Bucket(boltDataBucket).Get([]byte(key))
+	if buf == nil {
+		return nil, nil
+	}
+	var rec boltRecord
+	if err := json.Unmarshal(buf, &rec); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal record %s, err: %w", key, err)
+	}
+	if rec.ExpiresAt <= time.Now().UnixNano() {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// put stores the record under key, expiring after ttl.
+func (s *BoltStorage) put(tx *bolt.Tx, key string, rec *boltRecord, ttl time.Duration) error {
+	if err := s.del(tx, key); err != nil {
+		return err
+	}
+	rec.ExpiresAt = time.Now().Add(ttl).UnixNano()
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("fail to marshal record %s, err: %w", key, err)
+	}
+	if err := tx.Bucket(boltDataBucket).Put([]byte(key), buf); err != nil {
+		return fmt.Errorf("fail to put record %s, err: %w", key, err)
+	}
+	return tx.Bucket(boltExpiryBucket).Put(expiryKey(rec.ExpiresAt, key), nil)
+}
+
+// del removes the record stored under key together with its expiry entry.
+func (s *BoltStorage) del(tx *bolt.Tx, key string) error {
+	data := tx.Bucket(boltDataBucket)
+	buf := data.Get([]byte(key))
+	if buf == nil {
+		return nil
+	}
+	var rec boltRecord
+	if err := json.Unmarshal(buf, &rec); err == nil {
+		if err := tx.Bucket(boltExpiryBucket).Delete(expiryKey(rec.ExpiresAt, key)); err != nil {
+			return fmt.Errorf("fail to delete expiry of %s, err: %w", key, err)
+		}
+	}
+	return data.Delete([]byte(key))
+}
+
+func (s *BoltStorage) sweepLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(boltSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			_ = s.sweep()
+		}
+	}
+}
+
+// sweep removes every expired record, expired session indexes also remove the keys they track.
+func (s *BoltStorage) sweep() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		now := time.Now().UnixNano()
+		var expired []string
+		c := tx.Bucket(boltExpiryBucket).Cursor()
+		for k, _ := c.First(); k != nil && int64(binary.BigEndian.Uint64(k[:8])) <= now; k, _ = c.Next() {
+			expired = append(expired, string(k[8:]))
+		}
+		for _, key := range expired {
+			buf := tx.Bucket(boltDataBucket).Get([]byte(key))
+			var rec boltRecord
+			if buf != nil && json.Unmarshal(buf, &rec) == nil && rec.Kind == boltKindIndex {
+				for _, k := range rec.List {
+					if err := s.del(tx, k); err != nil {
+						return err
+					}
+				}
+			}
+			if err := s.del(tx, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStorage) SetSession(ctx context.Context, key string, participants []string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			rec = &boltRecord{Kind: boltKindList}
+		}
+		for _, p := range participants {
+			needAdd := true
+			for _, existingP := range rec.List {
+				if p == existingP {
+					needAdd = false
+					break
+				}
+			}
+			if needAdd {
+				rec.List = append(rec.List, p)
+			}
+		}
+		return s.put(tx, key, rec, s.defaultExpiration)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to set session %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// GetSession gets a session with a list of participants, a missing session has no participants.
+func (s *BoltStorage) GetSession(ctx context.Context, key string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	participants := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil || rec == nil {
+			return err
+		}
+		participants = append(participants, rec.List...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get session %s, err: %w", key, err)
+	}
+	return participants, nil
+}
+
+// DeleteSession deletes a session together with every key recorded in its session index.
+func (s *BoltStorage) DeleteSession(ctx context.Context, sessionID string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		indexKey := SessionIndexKey(sessionID)
+		rec, err := s.get(tx, indexKey)
+		if err != nil {
+			return err
+		}
+		keys := []string{SessionKey(sessionID), indexKey}
+		if rec != nil {
+			keys = append(keys, rec.List...)
+		}
+		for _, key := range keys {
+			if err := s.del(tx, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to delete session %s, err: %w", sessionID, err)
+	}
+	return nil
+}
+
+// AddSessionKey records the given key in the session index, so it will be removed when the session is deleted.
+func (s *BoltStorage) AddSessionKey(ctx context.Context, sessionID string, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		indexKey := SessionIndexKey(sessionID)
+		rec, err := s.get(tx, indexKey)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			rec = &boltRecord{Kind: boltKindIndex}
+		}
+		exist := false
+		for _, k := range rec.List {
+			if k == key {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			rec.List = append(rec.List, key)
+		}
+		if err := s.put(tx, indexKey, rec, s.defaultExpiration); err != nil {
+			return err
+		}
+		// the tracked keys expire with the index, so they are not read once the session expired
+		for _, k := range rec.List {
+			tracked, err := s.get(tx, k)
+			if err != nil {
+				return err
+			}
+			if tracked == nil {
+				continue
+			}
+			// a record is rewritten whole, skip the ones that already expire with the index
+			if d := time.Duration(tracked.ExpiresAt - rec.ExpiresAt); d > -time.Second && d < time.Second {
+				continue
+			}
+			if err := s.put(tx, k, tracked, s.defaultExpiration); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to add key %s to session index %s, err: %w", key, sessionID, err)
+	}
+	return nil
+}
+
+// GetMessages gets the messages stored under key, in the order they were added.
+func (s *BoltStorage) GetMessages(ctx context.Context, key string) ([]model.Message, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	messages := []model.Message{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil || rec == nil {
+			return err
+		}
+		messages = append(messages, rec.Messages...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get messages %s, err: %w", key, err)
+	}
+	return messages, nil
+}
+
+// SetMessage adds a message, a message with the same hash is only stored once.
+func (s *BoltStorage) SetMessage(ctx context.Context, key string, message model.Message) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			rec = &boltRecord{Kind: boltKindMessages}
+		}
+		for _, m := range rec.Messages {
+			if m.Hash == message.Hash { // skip the message if it already exists
+				return nil
+			}
+		}
+		rec.Messages = append(rec.Messages, message)
+		return s.put(tx, key, rec, s.defaultExpiration)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to set message, err: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessages deletes all the messages stored under key.
+func (s *BoltStorage) DeleteMessages(ctx context.Context, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	if err := s.db.Update(func(tx *bolt.Tx) error { return s.del(tx, key) }); err != nil {
+		return fmt.Errorf("fail to delete messages, err: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessage deletes a message in the given key with hash equals to the given hash
+func (s *BoltStorage) DeleteMessage(ctx context.Context, key string, hash string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil || rec == nil {
+			return err
+		}
+		for i, m := range rec.Messages {
+			if m.Hash == hash {
+				rec.Messages = append(rec.Messages[:i], rec.Messages[i+1:]...)
+				return s.put(tx, key, rec, s.defaultExpiration)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to delete message, err: %w", err)
+	}
+	return nil
+}
+
+func (s *BoltStorage) SetValue(ctx context.Context, key string, value string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return s.put(tx, key, &boltRecord{Kind: boltKindValue, Value: []byte(value)}, s.defaultUserExpire)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to set value %s, err: %w", key, err)
+	}
+	return nil
+}
+
+func (s *BoltStorage) GetValue(ctx context.Context, key string) (string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return "", ctx.Err()
+	}
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil {
+			return err
+		}
+		if rec == nil || rec.Kind != boltKindValue {
+			return ErrNotFound
+		}
+		value = rec.Value
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("fail to get value %s, err: %w", key, err)
+	}
+	return string(value), nil
+}
+
+func (s *BoltStorage) DeleteValue(ctx context.Context, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	if err := s.db.Update(func(tx *bolt.Tx) error { return s.del(tx, key) }); err != nil {
+		return fmt.Errorf("fail to delete value %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// AddSetMembers adds the members to the set stored under key, and refreshes its expiration.
+func (s *BoltStorage) AddSetMembers(ctx context.Context, key string, members ...string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil {
+			return err
+		}
+		if rec == nil || rec.Kind != boltKindSet {
+			rec = &boltRecord{Kind: boltKindSet}
+		}
+		for _, member := range members {
+			if !slices.Contains(rec.List, member) {
+				rec.List = append(rec.List, member)
+			}
+		}
+		return s.put(tx, key, rec, s.defaultUserExpire)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to add members to set %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// RemoveSetMember removes the member and counts the members left in the same transaction.
+func (s *BoltStorage) RemoveSetMember(ctx context.Context, key string, member string) (int, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	left := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil || rec == nil || rec.Kind != boltKindSet {
+			return err
+		}
+		rec.List = slices.DeleteFunc(rec.List, func(m string) bool { return m == member })
+		left = len(rec.List)
+		if left == 0 {
+			return s.del(tx, key)
+		}
+		return s.put(tx, key, rec, s.defaultUserExpire)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fail to remove member from set %s, err: %w", key, err)
+	}
+	return left, nil
+}
+
+func (s *BoltStorage) GetSetMembers(ctx context.Context, key string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	members := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, key)
+		if err != nil || rec == nil || rec.Kind != boltKindSet {
+			return err
+		}
+		members = append(members, rec.List...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get set %s, err: %w", key, err)
+	}
+	return members, nil
+}
+
+func (s *BoltStorage) Ping(ctx context.Context) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	return s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(boltDataBucket) == nil {
+			return errors.New("bolt data bucket is missing")
+		}
+		return nil
+	})
+}
+
+func (s *BoltStorage) Type() string {
+	return TypeBolt
+}
+
+// Close stops the sweeper and closes the database file.
+func (s *BoltStorage) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		s.wg.Wait()
+		s.closeErr = s.db.Close()
+	})
+	return s.closeErr
+}
+
+var _ Inspector = (*BoltStorage)(nil)
+
+func (s *BoltStorage) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	var keys []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltDataBucket).Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			rec, err := s.get(tx, string(k))
+			if err != nil {
+				return err
+			}
+			if rec != nil {
+				keys = append(keys, string(k))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to list keys %s, err: %w", prefix, err)
+	}
+	return keys, nil
+}
+
+func (s *BoltStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	var rec *boltRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rec, err = s.get(tx, key)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fail to get ttl of %s, err: %w", key, err)
+	}
+	if rec == nil {
+		return 0, fmt.Errorf("fail to get ttl of %s, err: %w", key, ErrNotFound)
+	}
+	return time.Until(time.Unix(0, rec.ExpiresAt)), nil
+}
+
+func (s *BoltStorage) SessionKeys(ctx context.Context, sessionID string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	keys := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		rec, err := s.get(tx, SessionIndexKey(sessionID))
+		if err != nil || rec == nil {
+			return err
+		}
+		keys = append(keys, rec.List...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to get keys of session %s, err: %w", sessionID, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+var _ UserStore = (*BoltStorage)(nil)
+
+// userKey is the key of a user in the users bucket, ordered by ID.
+func userKey(id int64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(id))
+	return buf
+}
+
+func (s *BoltStorage) ListUsers(ctx context.Context, afterID int64, limit int) ([]model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	users := []model.User{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltUsersBucket).Cursor()
+		for k, v := c.Seek(userKey(afterID + 1)); k != nil && len(users) < limit; k, v = c.Next() {
+			var u model.User
+			if err := json.Unmarshal(v, &u); err != nil {
+				return fmt.Errorf("fail to unmarshal user, err: %w", err)
+			}
+			users = append(users, u)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to list users, err: %w", err)
+	}
+	return users, nil
+}
+
+func (s *BoltStorage) GetUser(ctx context.Context, id int64) (model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return model.User{}, ctx.Err()
+	}
+	var u model.User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		buf := tx.Bucket(boltUsersBucket).Get(userKey(id))
+		if buf == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(buf, &u)
+	})
+	if err != nil {
+		return model.User{}, fmt.Errorf("fail to get user %d, err: %w", id, err)
+	}
+	return u, nil
+}
+
+func (s *BoltStorage) SaveUser(ctx context.Context, user *model.User) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	// the ID of a new user is only assigned once the transaction is committed
+	saved := *user
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltUsersBucket)
+		saved.ID = user.ID
+		if saved.ID == 0 {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			saved.ID = int64(seq)
+		} else if b.Get(userKey(saved.ID)) == nil {
+			return ErrNotFound
+		}
+		buf, err := json.Marshal(saved)
+		if err != nil {
+			return err
+		}
+		return b.Put(userKey(saved.ID), buf)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to save user %d, err: %w", user.ID, err)
+	}
+	user.ID = saved.ID
+	return nil
+}
+
+func (s *BoltStorage) DeleteUser(ctx context.Context, id int64) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltUsersBucket)
+		if b.Get(userKey(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete(userKey(id))
+	})
+	if err != nil {
+		return fmt.Errorf("fail to delete user %d, err: %w", id, err)
+	}
+	return nil
+}
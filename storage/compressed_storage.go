@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/metrics"
+)
+
+// compressionMarker starts the values written by CompressedStorage, it is followed by the format of the value.
+// Values without the marker were written before compression and are read as is.
+const compressionMarker = "\x00vrz"
+
+// formats of the values written by CompressedStorage
+const (
+	formatRaw  = 'r'
+	formatGzip = 'g'
+	formatZstd = 'z'
+)
+
+var _ Storage = (*CompressedStorage)(nil)
+
+// CompressedStorage compresses the values, payloads, setup messages and keysign results, from a minimum size.
+// A value is stored compressed only when it gets smaller. Reads decode every format whatever the configured algorithm,
+// so changing or disabling the algorithm keeps the stored values readable.
+type CompressedStorage struct {
+	Storage
+	algorithm string
+	minSize   int
+	encoder   *zstd.Encoder
+	decoder   *zstd.Decoder
+}
+
+// NewCompressedStorage returns a storage that compresses the values of s with cfg.Algorithm.
+func NewCompressedStorage(s Storage, cfg config.Compression) (*CompressedStorage, error) {
+	// the encoder and the decoder are safe for concurrent EncodeAll and DecodeAll
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create zstd encoder, err: %w", err)
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to create zstd decoder, err: %w", err)
+	}
+	algorithm := cfg.Algorithm
+	if algorithm == "" {
+		algorithm = config.CompressionNone
+	}
+	return &CompressedStorage{
+		Storage:   s,
+		algorithm: algorithm,
+		minSize:   int(cfg.MinSize),
+		encoder:   encoder,
+		decoder:   decoder,
+	}, nil
+}
+
+func (s *CompressedStorage) SetValue(ctx context.Context, key string, value string) error {
+	encoded, err := s.encode(value)
+	if err != nil {
+		return fmt.Errorf("fail to compress value %s, err: %w", key, err)
+	}
+	return s.Storage.SetValue(ctx, key, encoded)
+}
+
+func (s *CompressedStorage) GetValue(ctx context.Context, key string) (string, error) {
+	value, err := s.Storage.GetValue(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := s.decode(value)
+	if err != nil {
+		return "", fmt.Errorf("fail to decompress value %s, err: %w", key, err)
+	}
+	return decoded, nil
+}
+
+// encode returns the stored form of value.
+func (s *CompressedStorage) encode(value string) (string, error) {
+	if s.algorithm != config.CompressionNone && len(value) >= s.minSize {
+		var compressed []byte
+		var format byte
+		switch s.algorithm {
+		case config.CompressionGzip:
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			if _, err := io.WriteString(w, value); err != nil {
+				return "", err
+			}
+			if err := w.Close(); err != nil {
+				return "", err
+			}
+			compressed, format = buf.Bytes(), formatGzip
+		case config.CompressionZstd:
+			compressed, format = s.encoder.EncodeAll([]byte(value), nil), formatZstd
+		default:
+			return "", fmt.Errorf("unknown compression algorithm %s", s.algorithm)
+		}
+		if saved := len(value) - len(compressionMarker) - 1 - len(compressed); saved > 0 {
+			metrics.StorageCompressedValues.WithLabelValues(s.algorithm).Inc()
+			metrics.StorageCompressionSavedBytes.WithLabelValues(s.algorithm).Add(float64(saved))
+			return compressionMarker + string(format) + string(compressed), nil
+		}
+	}
+	if strings.HasPrefix(value, compressionMarker) {
+		// the marker is escaped, so the value isn't mistaken for a compressed one
+		return compressionMarker + string(formatRaw) + value, nil
+	}
+	return value, nil
+}
+
+// decode returns the value of its stored form.
+func (s *CompressedStorage) decode(stored string) (string, error) {
+	if !strings.HasPrefix(stored, compressionMarker) || len(stored) == len(compressionMarker) {
+		return stored, nil
+	}
+	data := stored[len(compressionMarker)+1:]
+	switch stored[len(compressionMarker)] {
+	case formatRaw:
+		return data, nil
+	case formatGzip:
+		r, err := gzip.NewReader(strings.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	case formatZstd:
+		buf, err := s.decoder.DecodeAll([]byte(data), nil)
+		if err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+	return "", fmt.Errorf("unknown value format %q", stored[len(compressionMarker)])
+}
+
+func (s *CompressedStorage) Close() error {
+	s.decoder.Close()
+	if err := s.encoder.Close(); err != nil {
+		return fmt.Errorf("fail to close zstd encoder, err: %w", err)
+	}
+	return s.Storage.Close()
+}
+
+var _ Unwrapper = (*CompressedStorage)(nil)
+
+// Unwrap returns the decorated storage.
+func (s *CompressedStorage) Unwrap() Storage {
+	return s.Storage
+}
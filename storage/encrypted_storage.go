@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vultisig/vultisig-relay/config"
+)
+
+// encryptionMarker starts the values written by EncryptedStorage. It is followed by the length of the key ID,
+// the key ID, the nonce and the AES-GCM sealed value. Values without the marker were written before encryption
+// was enabled and are read as is.
+const encryptionMarker = "\x00vre"
+
+var _ Storage = (*EncryptedStorage)(nil)
+
+// EncryptedStorage encrypts the values, payloads, setup messages and keysign results, with AES-256-GCM.
+// Values are encrypted with the first key and decrypted with the key whose ID they were written with, so keys
+// can be rotated by adding a new first key and dropping the old one once the values written with it expired.
+// The storage key is authenticated with the value, a value copied to another key doesn't decrypt.
+type EncryptedStorage struct {
+	Storage
+	writeKeyID string
+	keys       map[string]cipher.AEAD
+}
+
+// NewEncryptedStorage returns a storage that encrypts the values of s with the keys of cfg.
+func NewEncryptedStorage(s Storage, cfg config.Encryption) (*EncryptedStorage, error) {
+	keys := cfg.Keys
+	if cfg.KeyFile != "" {
+		fileKeys, err := readKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(append([]string{}, keys...), fileKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption key")
+	}
+	es := &EncryptedStorage{
+		Storage: s,
+		keys:    make(map[string]cipher.AEAD, len(keys)),
+	}
+	for _, k := range keys {
+		id, key, err := config.ParseEncryptionKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := es.keys[id]; ok {
+			return nil, fmt.Errorf("duplicate encryption key ID %s", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create cipher of encryption key %s, err: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create cipher of encryption key %s, err: %w", id, err)
+		}
+		es.keys[id] = aead
+		if es.writeKeyID == "" {
+			es.writeKeyID = id
+		}
+	}
+	return es, nil
+}
+
+// readKeyFile returns the keys of a key file, empty lines and lines starting with # are skipped.
+func readKeyFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open encryption key file, err: %w", err)
+	}
+	defer f.Close()
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("fail to read encryption key file, err: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *EncryptedStorage) SetValue(ctx context.Context, key string, value string) error {
+	aead := s.keys[s.writeKeyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("fail to generate nonce, err: %w", err)
+	}
+	header := encryptionMarker + string([]byte{byte(len(s.writeKeyID))}) + s.writeKeyID
+	sealed := aead.Seal([]byte(header+string(nonce)), nonce, []byte(value), []byte(key))
+	return s.Storage.SetValue(ctx, key, string(sealed))
+}
+
+func (s *EncryptedStorage) GetValue(ctx context.Context, key string) (string, error) {
+	stored, err := s.Storage.GetValue(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(stored, encryptionMarker) {
+		return stored, nil
+	}
+	data := stored[len(encryptionMarker):]
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return "", fmt.Errorf("encrypted value %s is truncated", key)
+	}
+	id := data[1 : 1+int(data[0])]
+	data = data[1+len(id):]
+	aead, ok := s.keys[id]
+	if !ok {
+		return "", fmt.Errorf("value %s is encrypted with unknown key %s", key, id)
+	}
+	if len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("encrypted value %s is truncated", key)
+	}
+	value, err := aead.Open(nil, []byte(data[:aead.NonceSize()]), []byte(data[aead.NonceSize():]), []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("fail to decrypt value %s, err: %w", key, err)
+	}
+	return string(value), nil
+}
+
+var _ Unwrapper = (*EncryptedStorage)(nil)
+
+// Unwrap returns the decorated storage.
+func (s *EncryptedStorage) Unwrap() Storage {
+	return s.Storage
+}
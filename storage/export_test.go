@@ -0,0 +1,6 @@
+package storage
+
+// SweepInMemory removes the expired items of an in-memory storage without waiting for the sweeper.
+func SweepInMemory(s Storage) {
+	s.(*InMemoryStorage).sweep()
+}
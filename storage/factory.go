@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/vultisig/vultisig-relay/config"
+)
+
+// New returns the storage backend selected by storage.type, wrapped with the decorators enabled in the configuration.
+func New(cfg *config.Config) (Storage, error) {
+	var s Storage
+	var err error
+	storageType := cfg.Storage.Type
+	if storageType == "" {
+		storageType = TypeRedis
+	}
+	switch storageType {
+	case TypeRedis:
+		s, err = NewRedisStorage(cfg.RedisServer, cfg.Storage.Expiration)
+	case TypeMemory:
+		s, err = NewInMemoryStorage(cfg.Storage.Expiration)
+	case TypeBolt:
+		s, err = NewBoltStorage(cfg.Storage)
+	case TypeSQL:
+		s, err = NewSQLStorage(cfg.ConnectionString, cfg.Storage.Expiration)
+	default:
+		return nil, fmt.Errorf("unknown storage type %s", storageType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to create %s storage, err: %w", storageType, err)
+	}
+	if cfg.Storage.Encryption.Enabled() {
+		if s, err = NewEncryptedStorage(s, cfg.Storage.Encryption); err != nil {
+			return nil, fmt.Errorf("fail to create encrypted storage, err: %w", err)
+		}
+	}
+	// values are compressed before they are encrypted, they are always decoded as they may have been compressed
+	// before compression was disabled
+	if s, err = NewCompressedStorage(s, cfg.Storage.Compression); err != nil {
+		return nil, err
+	}
+	if cfg.Storage.LegacyKeys {
+		s = NewLegacyKeyStorage(s)
+	}
+	return s, nil
+}
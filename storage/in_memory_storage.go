@@ -2,35 +2,124 @@ package storage
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/patrickmn/go-cache"
-
+	"github.com/vultisig/vultisig-relay/config"
 	"github.com/vultisig/vultisig-relay/contexthelper"
 	"github.com/vultisig/vultisig-relay/model"
 )
 
 var _ Storage = (*InMemoryStorage)(nil)
 
+// memorySweepInterval is how often expired items are removed from memory.
+const memorySweepInterval = time.Minute
+
+// InMemoryStorage keeps everything in process memory, it behaves like RedisStorage including expiration,
+// which makes it suitable for local development, CI and single-device demos.
 type InMemoryStorage struct {
-	cache *cache.Cache
+	defaultExpiration time.Duration
+	defaultUserExpire time.Duration
+	now               func() time.Time
+	// lock guards the items and the users
+	lock    sync.Mutex
+	items   map[string]memoryItem
+	users   map[int64]model.User
+	userSeq int64
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// memoryItem is a stored value with its expiration time.
+type memoryItem struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// sessionIndex is the set of keys that belong to a session.
+type sessionIndex map[string]struct{}
+
+// memorySet is a set stored with AddSetMembers.
+type memorySet map[string]struct{}
+
+func NewInMemoryStorage(expiration config.Expiration) (Storage, error) {
+	s := &InMemoryStorage{
+		defaultExpiration: expiration.SessionTTL(),
+		defaultUserExpire: expiration.ValueTTL(),
+		now:               time.Now,
+		items:             make(map[string]memoryItem),
+		users:             make(map[int64]model.User),
+		stop:              make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.sweepLoop()
+	return s, nil
+}
+
+// get returns the value stored under key, false when it doesn't exist or has expired. The lock must be held.
+func (s *InMemoryStorage) get(key string) (interface{}, bool) {
+	item, ok := s.items[key]
+	if !ok || !item.expiresAt.After(s.now()) {
+		return nil, false
+	}
+	return item.value, true
+}
+
+// set stores the value under key, expiring after ttl. The lock must be held.
+func (s *InMemoryStorage) set(key string, value interface{}, ttl time.Duration) {
+	s.items[key] = memoryItem{value: value, expiresAt: s.now().Add(ttl)}
+}
+
+// remove deletes the value stored under key, a session index also removes the keys it tracks. The lock must be held.
+func (s *InMemoryStorage) remove(key string) {
+	item, ok := s.items[key]
+	if !ok {
+		return
+	}
+	delete(s.items, key)
+	if idx, ok := item.value.(sessionIndex); ok {
+		for k := range idx {
+			delete(s.items, k)
+		}
+	}
+}
+
+func (s *InMemoryStorage) sweepLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(memorySweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
 }
 
-func NewInMemoryStorage() (Storage, error) {
-	return &InMemoryStorage{
-		cache: cache.New(time.Minute*5, time.Minute*10),
-	}, nil
+// sweep removes every expired item, expired session indexes also remove the keys they track.
+func (s *InMemoryStorage) sweep() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := s.now()
+	for key, item := range s.items {
+		if !item.expiresAt.After(now) {
+			s.remove(key)
+		}
+	}
 }
+
 func (s *InMemoryStorage) SetSession(ctx context.Context, key string, participants []string) error {
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return ctx.Err()
 	}
-	existingParticipants, err := s.GetSession(ctx, key)
-	if err != nil && !errors.Is(err, ErrNotFound) {
-		return fmt.Errorf("fail to get existing session %s, err: %w", key, err)
-	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	existingParticipants := s.getSession(key)
 	var participantsToAdd []string
 	for _, p := range participants {
 		needAdd := true
@@ -45,25 +134,66 @@ func (s *InMemoryStorage) SetSession(ctx context.Context, key string, participan
 			participantsToAdd = append(participantsToAdd, p)
 		}
 	}
-	s.cache.Set(key, append(existingParticipants, participantsToAdd...), cache.DefaultExpiration)
+	s.set(key, append(existingParticipants, participantsToAdd...), s.defaultExpiration)
 	return nil
 }
 
+// GetSession gets a session with a list of participants, a missing session has no participants.
 func (s *InMemoryStorage) GetSession(ctx context.Context, key string) ([]string, error) {
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return nil, ctx.Err()
 	}
-	if x, found := s.cache.Get(key); found {
-		return x.([]string), nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.getSession(key), nil
+}
+
+// getSession returns a copy of the participants, so callers can't modify the stored list. A key that holds
+// something else has no participants, as with the other backends. The lock must be held.
+func (s *InMemoryStorage) getSession(key string) []string {
+	if x, found := s.get(key); found {
+		if participants, ok := x.([]string); ok {
+			return append([]string{}, participants...)
+		}
+	}
+	return []string{}
+}
+
+func (s *InMemoryStorage) DeleteSession(ctx context.Context, sessionID string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
 	}
-	return nil, ErrNotFound
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.remove(SessionKey(sessionID))
+	// removing the index removes all the keys of the session
+	s.remove(SessionIndexKey(sessionID))
+	return nil
 }
 
-func (s *InMemoryStorage) DeleteSession(ctx context.Context, key string) error {
+func (s *InMemoryStorage) AddSessionKey(ctx context.Context, sessionID string, key string) error {
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return ctx.Err()
 	}
-	s.cache.Delete(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	indexKey := SessionIndexKey(sessionID)
+	// the stored index is never modified in place, a copy is stored instead
+	idx := sessionIndex{key: {}}
+	if x, found := s.get(indexKey); found {
+		for k := range x.(sessionIndex) {
+			idx[k] = struct{}{}
+		}
+	}
+	s.set(indexKey, idx, s.defaultExpiration)
+	// the tracked keys expire with the index, so they are not read once the session expired
+	expiresAt := s.items[indexKey].expiresAt
+	for k := range idx {
+		if item, ok := s.items[k]; ok && item.expiresAt.After(s.now()) {
+			item.expiresAt = expiresAt
+			s.items[k] = item
+		}
+	}
 	return nil
 }
 
@@ -71,27 +201,33 @@ func (s *InMemoryStorage) GetMessages(ctx context.Context, key string) ([]model.
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return nil, ctx.Err()
 	}
-	if x, found := s.cache.Get(key); found {
-		return x.([]model.Message), nil
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.getMessages(key), nil
+}
+
+// getMessages returns a copy of the messages, so callers can't modify the stored list. The lock must be held.
+func (s *InMemoryStorage) getMessages(key string) []model.Message {
+	if x, found := s.get(key); found {
+		return append([]model.Message{}, x.([]model.Message)...)
 	}
-	return []model.Message{}, nil
+	return []model.Message{}
 }
 
 func (s *InMemoryStorage) SetMessage(ctx context.Context, key string, message model.Message) error {
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return ctx.Err()
 	}
-	existingMessages, err := s.GetMessages(ctx, key)
-	if err != nil {
-		return fmt.Errorf("fail to get existing messages, err: %w", err)
-	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	existingMessages := s.getMessages(key)
 	for _, m := range existingMessages {
 		if m.Hash == message.Hash {
 			return nil
 		}
 	}
 	existingMessages = append(existingMessages, message)
-	s.cache.Set(key, existingMessages, cache.DefaultExpiration)
+	s.set(key, existingMessages, s.defaultExpiration)
 	return nil
 }
 
@@ -99,7 +235,9 @@ func (s *InMemoryStorage) DeleteMessages(ctx context.Context, key string) error
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return ctx.Err()
 	}
-	s.cache.Delete(key)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.remove(key)
 	return nil
 }
 
@@ -107,17 +245,16 @@ func (s *InMemoryStorage) DeleteMessage(ctx context.Context, key string, hash st
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return ctx.Err()
 	}
-	existingMessages, err := s.GetMessages(ctx, key)
-	if err != nil {
-		return fmt.Errorf("fail to get existing messages, err: %w", err)
-	}
-	var updatedMessages []model.Message
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	existingMessages := s.getMessages(key)
+	updatedMessages := make([]model.Message, 0, len(existingMessages))
 	for _, m := range existingMessages {
 		if m.Hash != hash {
 			updatedMessages = append(updatedMessages, m)
 		}
 	}
-	s.cache.Set(key, updatedMessages, cache.DefaultExpiration)
+	s.set(key, updatedMessages, s.defaultExpiration)
 	return nil
 }
 
@@ -125,7 +262,9 @@ func (s *InMemoryStorage) SetValue(ctx context.Context, key string, value string
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return ctx.Err()
 	}
-	s.cache.Set(key, value, cache.DefaultExpiration)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.set(key, value, s.defaultUserExpire)
 	return nil
 }
 
@@ -133,8 +272,202 @@ func (s *InMemoryStorage) GetValue(ctx context.Context, key string) (string, err
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return "", ctx.Err()
 	}
-	if x, found := s.cache.Get(key); found {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if x, found := s.get(key); found {
 		return x.(string), nil
 	}
-	return "", fmt.Errorf("value not found")
+	return "", fmt.Errorf("fail to get value %s, err: %w", key, ErrNotFound)
+}
+
+func (s *InMemoryStorage) DeleteValue(ctx context.Context, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.remove(key)
+	return nil
+}
+
+// AddSetMembers adds the members to the set stored under key, and refreshes its expiration.
+func (s *InMemoryStorage) AddSetMembers(ctx context.Context, key string, members ...string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	set := s.getSet(key)
+	for _, member := range members {
+		set[member] = struct{}{}
+	}
+	s.set(key, set, s.defaultUserExpire)
+	return nil
+}
+
+func (s *InMemoryStorage) RemoveSetMember(ctx context.Context, key string, member string) (int, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	set := s.getSet(key)
+	delete(set, member)
+	if len(set) == 0 {
+		s.remove(key)
+		return 0, nil
+	}
+	s.set(key, set, s.defaultUserExpire)
+	return len(set), nil
+}
+
+func (s *InMemoryStorage) GetSetMembers(ctx context.Context, key string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	members := []string{}
+	for member := range s.getSet(key) {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// getSet returns a copy of the set, as the stored one is never modified in place. The lock must be held.
+func (s *InMemoryStorage) getSet(key string) memorySet {
+	set := memorySet{}
+	if x, found := s.get(key); found {
+		if stored, ok := x.(memorySet); ok {
+			for member := range stored {
+				set[member] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+func (s *InMemoryStorage) Ping(ctx context.Context) error {
+	return contexthelper.CheckCancellation(ctx)
+}
+
+func (s *InMemoryStorage) Type() string {
+	return TypeMemory
+}
+
+// Close stops the sweeper and drops everything.
+func (s *InMemoryStorage) Close() error {
+	s.lock.Lock()
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.items = make(map[string]memoryItem)
+	s.users = make(map[int64]model.User)
+	s.lock.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+var _ Inspector = (*InMemoryStorage)(nil)
+
+func (s *InMemoryStorage) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var keys []string
+	for key := range s.items {
+		if _, found := s.get(key); found && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *InMemoryStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.get(key); !found {
+		return 0, fmt.Errorf("fail to get ttl of %s, err: %w", key, ErrNotFound)
+	}
+	return s.items[key].expiresAt.Sub(s.now()), nil
+}
+
+func (s *InMemoryStorage) SessionKeys(ctx context.Context, sessionID string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys := []string{}
+	if x, found := s.get(SessionIndexKey(sessionID)); found {
+		for key := range x.(sessionIndex) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+var _ UserStore = (*InMemoryStorage)(nil)
+
+func (s *InMemoryStorage) ListUsers(ctx context.Context, afterID int64, limit int) ([]model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	users := make([]model.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	return pageUsers(users, afterID, limit), nil
+}
+
+func (s *InMemoryStorage) GetUser(ctx context.Context, id int64) (model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return model.User{}, ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return model.User{}, fmt.Errorf("fail to get user %d, err: %w", id, ErrNotFound)
+	}
+	return u, nil
+}
+
+func (s *InMemoryStorage) SaveUser(ctx context.Context, user *model.User) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if user.ID == 0 {
+		s.userSeq++
+		user.ID = s.userSeq
+	} else if _, ok := s.users[user.ID]; !ok {
+		return fmt.Errorf("fail to save user %d, err: %w", user.ID, ErrNotFound)
+	}
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *InMemoryStorage) DeleteUser(ctx context.Context, id int64) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return fmt.Errorf("fail to delete user %d, err: %w", id, ErrNotFound)
+	}
+	delete(s.users, id)
+	return nil
 }
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Inspector is implemented by the storages that operators can inspect, it only exposes keys and expirations,
+// the data itself is read through Storage.
+type Inspector interface {
+	// Keys returns the keys that start with prefix and have not expired.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// TTL returns how long the key lives, 0 when it doesn't expire, or ErrNotFound when it doesn't exist.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// SessionKeys returns the keys recorded in the session index.
+	SessionKeys(ctx context.Context, sessionID string) ([]string, error)
+}
+
+// Unwrapper is implemented by storages that decorate another storage.
+type Unwrapper interface {
+	Unwrap() Storage
+}
+
+// AsInspector returns the Inspector of the storage, looking through decorators.
+func AsInspector(s Storage) (Inspector, bool) {
+	return unwrapAs[Inspector](s)
+}
+
+// unwrapAs returns the first storage of the decorator chain that implements T.
+func unwrapAs[T any](s Storage) (T, bool) {
+	for s != nil {
+		if t, ok := s.(T); ok {
+			return t, true
+		}
+		u, ok := s.(Unwrapper)
+		if !ok {
+			break
+		}
+		s = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// ListSessions returns the sorted IDs of the sessions that have participants or tracked keys.
+func ListSessions(ctx context.Context, inspector Inspector) ([]string, error) {
+	found := make(map[string]struct{})
+	for _, kind := range []KeyKind{KindSession, KindSessionIndex} {
+		keys, err := inspector.Keys(ctx, KindPrefix(kind))
+		if err != nil {
+			return nil, fmt.Errorf("fail to list %s keys, err: %w", kind, err)
+		}
+		for _, key := range keys {
+			_, segments, err := ParseKey(key)
+			if err != nil {
+				continue
+			}
+			found[segments[0]] = struct{}{}
+		}
+	}
+	sessions := make([]string, 0, len(found))
+	for sessionID := range found {
+		sessions = append(sessions, sessionID)
+	}
+	sort.Strings(sessions)
+	return sessions, nil
+}
+
+// Session states reported by InspectSession.
+const (
+	SessionStateJoining   = "joining"
+	SessionStateStarted   = "started"
+	SessionStateCompleted = "completed"
+)
+
+// SessionInfo describes a session for operators, it never includes message bodies or stored values.
+type SessionInfo struct {
+	SessionID    string   `json:"session_id"`
+	State        string   `json:"state"`
+	Participants []string `json:"participants"`
+	Started      []string `json:"started"`
+	Completed    []string `json:"completed"`
+	// TTLSeconds is the time left before the session expires
+	TTLSeconds int64       `json:"ttl_seconds"`
+	Inboxes    []InboxInfo `json:"inboxes"`
+	Keys       []KeyInfo   `json:"keys"`
+}
+
+// InboxInfo is the number of messages waiting in the inbox of a participant.
+type InboxInfo struct {
+	ParticipantID string `json:"participant_id"`
+	MessageID     string `json:"message_id,omitempty"`
+	Messages      int    `json:"messages"`
+}
+
+// KeyInfo is a key tracked by the session index.
+type KeyInfo struct {
+	Key        string  `json:"key"`
+	Kind       KeyKind `json:"kind"`
+	TTLSeconds int64   `json:"ttl_seconds"`
+}
+
+// InspectSession collects the state of a session, it returns ErrNotFound when nothing is stored for the session.
+func InspectSession(ctx context.Context, s Storage, inspector Inspector, sessionID string) (SessionInfo, error) {
+	info := SessionInfo{
+		SessionID: sessionID,
+		Inboxes:   []InboxInfo{},
+		Keys:      []KeyInfo{},
+	}
+	var err error
+	if info.Participants, err = s.GetSession(ctx, SessionKey(sessionID)); err != nil {
+		return info, err
+	}
+	if info.Started, err = s.GetSession(ctx, StartKey(sessionID)); err != nil {
+		return info, err
+	}
+	if info.Completed, err = s.GetSession(ctx, CompleteKey(sessionID)); err != nil {
+		return info, err
+	}
+	keys, err := inspector.SessionKeys(ctx, sessionID)
+	if err != nil {
+		return info, err
+	}
+	if len(info.Participants) == 0 && len(keys) == 0 {
+		return info, fmt.Errorf("fail to inspect session %s, err: %w", sessionID, ErrNotFound)
+	}
+	for _, key := range keys {
+		kind, segments, err := ParseKey(key)
+		if err != nil {
+			continue
+		}
+		ttl, err := inspector.TTL(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			// tracked keys are only created on the first write, or may have been consumed already
+			continue
+		}
+		if err != nil {
+			return info, err
+		}
+		info.Keys = append(info.Keys, KeyInfo{Key: key, Kind: kind, TTLSeconds: int64(ttl.Seconds())})
+		if kind == KindMessage {
+			messages, err := s.GetMessages(ctx, key)
+			if err != nil {
+				return info, err
+			}
+			info.Inboxes = append(info.Inboxes, InboxInfo{ParticipantID: segments[1], MessageID: segments[2], Messages: len(messages)})
+		}
+	}
+	ttl, err := inspector.TTL(ctx, SessionKey(sessionID))
+	if errors.Is(err, ErrNotFound) {
+		ttl, err = inspector.TTL(ctx, SessionIndexKey(sessionID))
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return info, err
+	}
+	info.TTLSeconds = int64(ttl.Seconds())
+	info.State = sessionState(info)
+	return info, nil
+}
+
+func sessionState(info SessionInfo) string {
+	if len(info.Started) == 0 {
+		return SessionStateJoining
+	}
+	completed := make(map[string]bool, len(info.Completed))
+	for _, p := range info.Completed {
+		completed[p] = true
+	}
+	for _, p := range info.Started {
+		if !completed[p] {
+			return SessionStateStarted
+		}
+	}
+	return SessionStateCompleted
+}
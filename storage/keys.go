@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keyPrefix is prepended to every key written by the relay, the version allows the layout to change later.
+const keyPrefix = "relay:v1"
+
+// keySeparator separates the segments of a key, it is escaped inside segments.
+const keySeparator = ":"
+
+// KeyKind is the type of the data stored under a key.
+type KeyKind string
+
+const (
+	KindSession         KeyKind = "session"
+	KindSessionIndex    KeyKind = "idx"
+	KindStart           KeyKind = "start"
+	KindComplete        KeyKind = "complete"
+	KindMessage         KeyKind = "msg"
+	KindSetup           KeyKind = "setup"
+	KindKeysignComplete KeyKind = "keysign"
+	KindPayload         KeyKind = "payload"
+	KindUpload          KeyKind = "upload"
+	KindPayloadChunk    KeyKind = "chunk"
+	KindPayloadAlias    KeyKind = "alias"
+	KindPayloadReaders  KeyKind = "readers"
+	KindPayloadAliases  KeyKind = "aliases"
+	KindSessionPayloads KeyKind = "payloads"
+)
+
+// keySegments is the number of segments following the kind for each key kind.
+var keySegments = map[KeyKind]int{
+	KindSession:         1,
+	KindSessionIndex:    1,
+	KindStart:           1,
+	KindComplete:        1,
+	KindMessage:         3,
+	KindSetup:           2,
+	KindKeysignComplete: 2,
+	KindPayload:         1,
+	KindUpload:          1,
+	KindPayloadChunk:    2,
+	KindPayloadAlias:    1,
+	KindPayloadReaders:  1,
+	KindPayloadAliases:  1,
+	KindSessionPayloads: 1,
+}
+
+var segmentEscaper = strings.NewReplacer("%", "%25", keySeparator, "%3A", "{", "%7B", "}", "%7D")
+
+var segmentUnescaper = strings.NewReplacer("%3A", keySeparator, "%7B", "{", "%7D", "}", "%25", "%")
+
+// buildKey joins the escaped segments of a key. The first segment is wrapped in a redis cluster hash tag,
+// so all the keys of a session are stored in the same slot and multi-key operations stay valid.
+func buildKey(kind KeyKind, segments ...string) string {
+	var sb strings.Builder
+	sb.WriteString(keyPrefix)
+	sb.WriteString(keySeparator)
+	sb.WriteString(string(kind))
+	for i, segment := range segments {
+		sb.WriteString(keySeparator)
+		if i == 0 {
+			sb.WriteString("{" + segmentEscaper.Replace(segment) + "}")
+			continue
+		}
+		sb.WriteString(segmentEscaper.Replace(segment))
+	}
+	return sb.String()
+}
+
+// SessionKey returns the key of the participant list of a session.
+func SessionKey(sessionID string) string {
+	return buildKey(KindSession, sessionID)
+}
+
+// SessionIndexKey returns the key of the index that tracks all keys belonging to a session.
+func SessionIndexKey(sessionID string) string {
+	return buildKey(KindSessionIndex, sessionID)
+}
+
+// StartKey returns the key of the participants that started the TSS session.
+func StartKey(sessionID string) string {
+	return buildKey(KindStart, sessionID)
+}
+
+// CompleteKey returns the key of the participants that completed the TSS session.
+func CompleteKey(sessionID string) string {
+	return buildKey(KindComplete, sessionID)
+}
+
+// MessageKey returns the key of the inbox of a participant, messageID is optional.
+func MessageKey(sessionID, participantID, messageID string) string {
+	return buildKey(KindMessage, sessionID, participantID, messageID)
+}
+
+// SetupKey returns the key of the setup message of a session, messageID is optional.
+func SetupKey(sessionID, messageID string) string {
+	return buildKey(KindSetup, sessionID, messageID)
+}
+
+// KeysignCompleteKey returns the key of the keysign result of a session and message.
+func KeysignCompleteKey(sessionID, messageID string) string {
+	return buildKey(KindKeysignComplete, sessionID, messageID)
+}
+
+// PayloadKey returns the key of a payload addressed by its hash.
+func PayloadKey(hash string) string {
+	return buildKey(KindPayload, hash)
+}
+
+// UploadKey returns the key of the state of a chunked payload upload.
+func UploadKey(uploadID string) string {
+	return buildKey(KindUpload, uploadID)
+}
+
+// PayloadChunkKey returns the key of a chunk of the payload stored with the ID, chunks are numbered from 0.
+func PayloadChunkKey(payloadID string, index int) string {
+	return buildKey(KindPayloadChunk, payloadID, strconv.Itoa(index))
+}
+
+// PayloadAliasKey returns the key of the SHA-256 of the payload with an ID of another algorithm.
+func PayloadAliasKey(payloadID string) string {
+	return buildKey(KindPayloadAlias, payloadID)
+}
+
+// PayloadReadersKey returns the key of the set of sessions that can read the payload with the SHA-256.
+func PayloadReadersKey(hash string) string {
+	return buildKey(KindPayloadReaders, hash)
+}
+
+// PayloadAliasesKey returns the key of the set of IDs of other algorithms the payload with the SHA-256 is stored with.
+func PayloadAliasesKey(hash string) string {
+	return buildKey(KindPayloadAliases, hash)
+}
+
+// SessionPayloadsKey returns the key of the set of payloads referenced by a session.
+func SessionPayloadsKey(sessionID string) string {
+	return buildKey(KindSessionPayloads, sessionID)
+}
+
+// ParseKey splits a key created by the key builder into its kind and unescaped segments.
+func ParseKey(key string) (KeyKind, []string, error) {
+	rest, found := strings.CutPrefix(key, keyPrefix+keySeparator)
+	if !found {
+		return "", nil, fmt.Errorf("key %s does not start with %s", key, keyPrefix)
+	}
+	parts := strings.Split(rest, keySeparator)
+	kind := KeyKind(parts[0])
+	expected, ok := keySegments[kind]
+	if !ok {
+		return "", nil, fmt.Errorf("key %s has unknown kind %s", key, kind)
+	}
+	segments := parts[1:]
+	if len(segments) != expected {
+		return "", nil, fmt.Errorf("key %s has %d segments, expected %d", key, len(segments), expected)
+	}
+	tag, ok := strings.CutPrefix(segments[0], "{")
+	if !ok || !strings.HasSuffix(tag, "}") {
+		return "", nil, fmt.Errorf("key %s has no hash tag", key)
+	}
+	segments[0] = strings.TrimSuffix(tag, "}")
+	for i, segment := range segments {
+		segments[i] = segmentUnescaper.Replace(segment)
+	}
+	return kind, segments, nil
+}
+
+// LegacyKey returns the key that relay versions before the key builder used for the same data.
+func LegacyKey(key string) (string, error) {
+	kind, segments, err := ParseKey(key)
+	if err != nil {
+		return "", err
+	}
+	switch kind {
+	case KindSession, KindPayload:
+		return segments[0], nil
+	case KindStart, KindComplete:
+		return fmt.Sprintf("%s-%s", kind, segments[0]), nil
+	case KindMessage:
+		if segments[2] == "" {
+			return fmt.Sprintf("%s-%s", segments[0], segments[1]), nil
+		}
+		return fmt.Sprintf("%s-%s-%s", segments[0], segments[1], segments[2]), nil
+	case KindSetup:
+		if segments[1] == "" {
+			return fmt.Sprintf("setup-%s", segments[0]), nil
+		}
+		return fmt.Sprintf("setup-%s-%s", segments[0], segments[1]), nil
+	case KindKeysignComplete:
+		return fmt.Sprintf("keysign-%s-%s-complete", segments[0], segments[1]), nil
+	default:
+		return "", fmt.Errorf("key %s has no legacy equivalent", key)
+	}
+}
+
+// KindPrefix returns the prefix shared by every key of the kind, to list them.
+func KindPrefix(kind KeyKind) string {
+	return keyPrefix + keySeparator + string(kind) + keySeparator
+}
@@ -0,0 +1,153 @@
+package storage_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+func TestKeyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		kind     storage.KeyKind
+		segments []string
+	}{
+		{name: "plain", key: storage.MessageKey("session", "alice", "msg1"), kind: storage.KindMessage, segments: []string{"session", "alice", "msg1"}},
+		{name: "empty segment", key: storage.MessageKey("session", "alice", ""), kind: storage.KindMessage, segments: []string{"session", "alice", ""}},
+		{name: "empty segments", key: storage.MessageKey("session", "", ""), kind: storage.KindMessage, segments: []string{"session", "", ""}},
+		{name: "separator", key: storage.MessageKey("a:b", "c:", ":d"), kind: storage.KindMessage, segments: []string{"a:b", "c:", ":d"}},
+		{name: "hash tag braces", key: storage.SetupKey("{a}", "}b{"), kind: storage.KindSetup, segments: []string{"{a}", "}b{"}},
+		{name: "escape sequences", key: storage.SetupKey("%3A", "%7B%25"), kind: storage.KindSetup, segments: []string{"%3A", "%7B%25"}},
+		{name: "percent", key: storage.KeysignCompleteKey("100%", "%"), kind: storage.KindKeysignComplete, segments: []string{"100%", "%"}},
+		{name: "legacy separator", key: storage.SessionKey("a-b-c"), kind: storage.KindSession, segments: []string{"a-b-c"}},
+		{name: "unicode", key: storage.PayloadKey("ключ"), kind: storage.KindPayload, segments: []string{"ключ"}},
+		{name: "chunk", key: storage.PayloadChunkKey("id", 12), kind: storage.KindPayloadChunk, segments: []string{"id", "12"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, segments, err := storage.ParseKey(tt.key)
+			if err != nil {
+				t.Fatalf("fail to parse %s, err: %v", tt.key, err)
+			}
+			if kind != tt.kind || !reflect.DeepEqual(segments, tt.segments) {
+				t.Fatalf("%s: expected %s %q, got %s %q", tt.key, tt.kind, tt.segments, kind, segments)
+			}
+			// the only braces of a key are its hash tag, so redis cluster hashes the first segment
+			if strings.Count(tt.key, "{") != 1 || strings.Count(tt.key, "}") != 1 {
+				t.Fatalf("%s: expected a single hash tag", tt.key)
+			}
+		})
+	}
+}
+
+func TestKeysDontCollide(t *testing.T) {
+	// IDs that would collide if segments were joined without escaping
+	keys := []string{
+		storage.MessageKey("a:b", "c", ""),
+		storage.MessageKey("a", "b:c", ""),
+		storage.MessageKey("a", "b", "c"),
+		storage.MessageKey("a:b:c", "", ""),
+		storage.MessageKey("a", "", "b:c"),
+		storage.MessageKey("a%3Ab", "c", ""),
+		storage.MessageKey("{a}", "b", ""),
+		storage.MessageKey("a", "b-c", ""),
+		storage.MessageKey("a-b", "c", ""),
+		storage.SetupKey("a", "b"),
+		storage.SetupKey("a-b", ""),
+		storage.SetupKey("a:b", ""),
+		storage.SessionKey("a"),
+		storage.SessionKey("a-b"),
+		storage.SessionIndexKey("a"),
+		storage.StartKey("a"),
+		storage.CompleteKey("a"),
+		storage.KeysignCompleteKey("a", "b"),
+		storage.KeysignCompleteKey("a-b", ""),
+	}
+	seen := make(map[string]int)
+	for i, key := range keys {
+		if j, ok := seen[key]; ok {
+			t.Fatalf("keys %d and %d collide: %s", j, i, key)
+		}
+		seen[key] = i
+	}
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "legacy key", key: "session-alice"},
+		{name: "other version", key: "relay:v2:session:{a}"},
+		{name: "unknown kind", key: "relay:v1:unknown:{a}"},
+		{name: "missing segment", key: "relay:v1:msg:{a}:b"},
+		{name: "extra segment", key: "relay:v1:session:{a}:b"},
+		{name: "unescaped separator", key: "relay:v1:setup:{a:b}:"},
+		{name: "no hash tag", key: "relay:v1:session:a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind, segments, err := storage.ParseKey(tt.key); err == nil {
+				t.Fatalf("%s: expected an error, got %s %q", tt.key, kind, segments)
+			}
+		})
+	}
+}
+
+// clusterSlot returns the redis cluster slot of a key: the CRC16 of its hash tag, or of the whole key without one.
+func clusterSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+func TestSessionKeysShareSlot(t *testing.T) {
+	// slots reported by CLUSTER KEYSLOT of a real redis
+	for key, slot := range map[string]int{"foo": 12182, "somekey": 11058, "{user1000}.following": 3443} {
+		if got := clusterSlot(key); got != slot {
+			t.Fatalf("clusterSlot(%s) = %d, expected %d", key, got, slot)
+		}
+	}
+	// an empty session ID is an empty hash tag, which redis ignores, the relay refuses empty session IDs
+	for _, sessionID := range []string{"session", "a:b", "{a}", "}a{", "a%7B", "ключ"} {
+		keys := []string{
+			storage.SessionKey(sessionID),
+			storage.SessionIndexKey(sessionID),
+			storage.StartKey(sessionID),
+			storage.CompleteKey(sessionID),
+			storage.MessageKey(sessionID, "alice", ""),
+			storage.MessageKey(sessionID, "{bob}", "msg:1"),
+			storage.SetupKey(sessionID, ""),
+			storage.SetupKey(sessionID, "{msg}"),
+			storage.KeysignCompleteKey(sessionID, "msg"),
+			storage.SessionPayloadsKey(sessionID),
+		}
+		slot := clusterSlot(keys[0])
+		for _, key := range keys[1:] {
+			if got := clusterSlot(key); got != slot {
+				t.Fatalf("session %q: %s is in slot %d, %s in slot %d", sessionID, keys[0], slot, key, got)
+			}
+		}
+	}
+	// the chunks of a payload share the slot of its ID
+	if clusterSlot(storage.PayloadChunkKey("id", 0)) != clusterSlot(storage.PayloadChunkKey("id", 7)) {
+		t.Fatal("expected the chunks of a payload in the same slot")
+	}
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vultisig/vultisig-relay/model"
+)
+
+var _ Storage = (*LegacyKeyStorage)(nil)
+
+// LegacyKeyStorage wraps a Storage while the key builder is rolled out.
+// Writes only go to the new keys, reads merge in whatever is still stored under the legacy keys,
+// and deletes remove both, so ceremonies started against an older relay can still finish.
+type LegacyKeyStorage struct {
+	Storage
+}
+
+// NewLegacyKeyStorage returns a storage that can read keys written by older relay versions.
+func NewLegacyKeyStorage(s Storage) *LegacyKeyStorage {
+	return &LegacyKeyStorage{
+		Storage: s,
+	}
+}
+
+func (s *LegacyKeyStorage) GetSession(ctx context.Context, key string) ([]string, error) {
+	participants, err := s.Storage.GetSession(ctx, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	notFound := errors.Is(err, ErrNotFound)
+	legacyKey, err := LegacyKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get legacy key of %s, err: %w", key, err)
+	}
+	legacyParticipants, err := s.Storage.GetSession(ctx, legacyKey)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	for _, p := range legacyParticipants {
+		exist := false
+		for _, existingP := range participants {
+			if p == existingP {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			participants = append(participants, p)
+		}
+	}
+	if notFound && len(participants) == 0 {
+		return nil, ErrNotFound
+	}
+	return participants, nil
+}
+
+func (s *LegacyKeyStorage) DeleteSession(ctx context.Context, sessionID string) error {
+	if err := s.Storage.DeleteSession(ctx, sessionID); err != nil {
+		return err
+	}
+	// legacy relays had no session index, so remove the keys that can be derived from the session
+	participants, err := s.Storage.GetSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("fail to get legacy session %s, err: %w", sessionID, err)
+	}
+	keys := []string{
+		sessionID,
+		fmt.Sprintf("start-%s", sessionID),
+		fmt.Sprintf("complete-%s", sessionID),
+		fmt.Sprintf("setup-%s", sessionID),
+	}
+	for _, p := range participants {
+		// the inbox of p is also the participant list of session <sessionID>-<p>
+		exists, err := s.legacySessionExists(ctx, fmt.Sprintf("%s-%s", sessionID, p))
+		if err != nil {
+			return err
+		}
+		if !exists {
+			keys = append(keys, fmt.Sprintf("%s-%s", sessionID, p))
+		}
+	}
+	suffixed, err := s.legacyMessageKeys(ctx, sessionID, participants)
+	if err != nil {
+		return err
+	}
+	keys = append(keys, suffixed...)
+	for _, key := range keys {
+		if err := s.Storage.DeleteMessages(ctx, key); err != nil {
+			return fmt.Errorf("fail to delete legacy key %s, err: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// legacyMessageKeys lists the legacy keys that end with a message ID, they can only be found by listing keys,
+// so they are left to expire when the storage can't be inspected. Legacy keys join IDs with -, so a key only
+// matches when its message ID has no -, and a key that also belongs to a session that exists, such as setup-a-b
+// of session a-b when session a is deleted, is skipped.
+func (s *LegacyKeyStorage) legacyMessageKeys(ctx context.Context, sessionID string, participants []string) ([]string, error) {
+	inspector, ok := AsInspector(s.Storage)
+	if !ok {
+		return nil, nil
+	}
+	var keys []string
+	// match adds the keys made of prefix, a message ID and suffix, unless other names a session that exists
+	match := func(prefix, suffix string, other func(messageID string) string) error {
+		found, err := inspector.Keys(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("fail to list legacy keys %s, err: %w", prefix, err)
+		}
+		for _, key := range found {
+			messageID, ok := strings.CutSuffix(strings.TrimPrefix(key, prefix), suffix)
+			if !ok || messageID == "" || strings.Contains(messageID, "-") {
+				continue
+			}
+			if other != nil {
+				exists, err := s.legacySessionExists(ctx, other(messageID))
+				if err != nil {
+					return err
+				}
+				if exists {
+					continue
+				}
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	}
+	if err := match(fmt.Sprintf("setup-%s-", sessionID), "", func(messageID string) string {
+		return fmt.Sprintf("%s-%s", sessionID, messageID)
+	}); err != nil {
+		return nil, err
+	}
+	if err := match(fmt.Sprintf("keysign-%s-", sessionID), "-complete", nil); err != nil {
+		return nil, err
+	}
+	for _, p := range participants {
+		other := fmt.Sprintf("%s-%s", sessionID, p)
+		if err := match(other+"-", "", func(string) string { return other }); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// legacySessionExists returns true when a legacy session has participants.
+func (s *LegacyKeyStorage) legacySessionExists(ctx context.Context, sessionID string) (bool, error) {
+	participants, err := s.Storage.GetSession(ctx, sessionID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, fmt.Errorf("fail to get legacy session %s, err: %w", sessionID, err)
+	}
+	return len(participants) > 0, nil
+}
+
+func (s *LegacyKeyStorage) GetMessages(ctx context.Context, key string) ([]model.Message, error) {
+	messages, err := s.Storage.GetMessages(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	legacyKey, err := LegacyKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get legacy key of %s, err: %w", key, err)
+	}
+	legacyMessages, err := s.Storage.GetMessages(ctx, legacyKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range legacyMessages {
+		exist := false
+		for _, existingM := range messages {
+			if m.Hash == existingM.Hash {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			messages = append(messages, m)
+		}
+	}
+	return messages, nil
+}
+
+func (s *LegacyKeyStorage) DeleteMessages(ctx context.Context, key string) error {
+	if err := s.Storage.DeleteMessages(ctx, key); err != nil {
+		return err
+	}
+	legacyKey, err := LegacyKey(key)
+	if err != nil {
+		return fmt.Errorf("fail to get legacy key of %s, err: %w", key, err)
+	}
+	return s.Storage.DeleteMessages(ctx, legacyKey)
+}
+
+func (s *LegacyKeyStorage) DeleteMessage(ctx context.Context, key string, hash string) error {
+	if err := s.Storage.DeleteMessage(ctx, key, hash); err != nil {
+		return err
+	}
+	legacyKey, err := LegacyKey(key)
+	if err != nil {
+		return fmt.Errorf("fail to get legacy key of %s, err: %w", key, err)
+	}
+	return s.Storage.DeleteMessage(ctx, legacyKey, hash)
+}
+
+func (s *LegacyKeyStorage) GetValue(ctx context.Context, key string) (string, error) {
+	value, err := s.Storage.GetValue(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	legacyKey, legacyErr := LegacyKey(key)
+	if legacyErr != nil {
+		return "", err
+	}
+	legacyValue, legacyErr := s.Storage.GetValue(ctx, legacyKey)
+	if legacyErr != nil {
+		return "", err
+	}
+	return legacyValue, nil
+}
+
+func (s *LegacyKeyStorage) DeleteValue(ctx context.Context, key string) error {
+	if err := s.Storage.DeleteValue(ctx, key); err != nil {
+		return err
+	}
+	legacyKey, err := LegacyKey(key)
+	if err != nil {
+		// keys written after the key builder have no legacy equivalent
+		return nil
+	}
+	return s.Storage.DeleteValue(ctx, legacyKey)
+}
+
+var _ Unwrapper = (*LegacyKeyStorage)(nil)
+
+// Unwrap returns the decorated storage.
+func (s *LegacyKeyStorage) Unwrap() Storage {
+	return s.Storage
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PayloadChunkSize is the size of the chunks payloads are stored in, so a payload is never held whole in memory.
+const PayloadChunkSize = 1 << 20
+
+// payloadManifestMarker starts the manifest stored under the key of a payload. Payloads stored before they were
+// chunked are a single value without the marker.
+const payloadManifestMarker = "\x00vrm"
+
+// ErrCorruptPayload is returned when a stored payload doesn't match its hash.
+var ErrCorruptPayload = errors.New("payload does not match its hash")
+
+// PayloadChunk describes a stored chunk of a payload.
+type PayloadChunk struct {
+	Size int64 `json:"size"`
+	// Hash is the hex encoded SHA-256 of the chunk, it is verified when the chunk is read
+	Hash string `json:"hash"`
+}
+
+// PayloadManifest lists the chunks of a payload, they are stored under PayloadChunkKey(ID, index).
+type PayloadManifest struct {
+	ID     string         `json:"id"`
+	Size   int64          `json:"size"`
+	Chunks []PayloadChunk `json:"chunks"`
+	// Scoped is set once the readers of the payload are listed under PayloadReadersKey, anyone can read the
+	// payloads stored before
+	Scoped bool `json:"scoped,omitempty"`
+}
+
+// NewPayloadID returns a random ID to store the chunks of a payload under.
+func NewPayloadID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("fail to generate payload ID, err: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// PayloadWriter stores what is written to it in chunks of PayloadChunkSize and lists them in its manifest.
+// As with a bufio.Writer, once a chunk fails to be stored every later write returns the same error.
+type PayloadWriter struct {
+	ctx      context.Context
+	s        Storage
+	manifest PayloadManifest
+	buf      []byte
+	err      error
+}
+
+// NewPayloadWriter returns a writer appending chunks to manifest, which has no chunks for a new payload.
+func NewPayloadWriter(ctx context.Context, s Storage, manifest PayloadManifest) *PayloadWriter {
+	return &PayloadWriter{ctx: ctx, s: s, manifest: manifest}
+}
+
+func (w *PayloadWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 && w.err == nil {
+		k := min(len(p), PayloadChunkSize-len(w.buf))
+		w.buf = append(w.buf, p[:k]...)
+		p = p[k:]
+		n += k
+		if len(w.buf) == PayloadChunkSize {
+			_ = w.Flush()
+		}
+	}
+	return n, w.err
+}
+
+// Flush stores the buffered bytes as a chunk, shorter than PayloadChunkSize unless the buffer is full.
+func (w *PayloadWriter) Flush() error {
+	if w.err != nil || len(w.buf) == 0 {
+		return w.err
+	}
+	index := len(w.manifest.Chunks)
+	if err := w.s.SetValue(w.ctx, PayloadChunkKey(w.manifest.ID, index), string(w.buf)); err != nil {
+		w.err = fmt.Errorf("fail to store chunk %d, err: %w", index, err)
+		return w.err
+	}
+	sum := sha256.Sum256(w.buf)
+	w.manifest.Chunks = append(w.manifest.Chunks, PayloadChunk{Size: int64(len(w.buf)), Hash: hex.EncodeToString(sum[:])})
+	w.manifest.Size += int64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Err returns the error of the chunk that failed to be stored.
+func (w *PayloadWriter) Err() error {
+	return w.err
+}
+
+// Discard deletes the chunks stored so far and drops the buffered bytes, for a payload that is rejected.
+// The chunks are deleted even when the context of the writer is cancelled, they expire when they can't be.
+func (w *PayloadWriter) Discard() error {
+	w.buf = w.buf[:0]
+	if err := deleteChunks(context.WithoutCancel(w.ctx), w.s, w.manifest); err != nil {
+		return err
+	}
+	w.manifest.Chunks, w.manifest.Size = nil, 0
+	return nil
+}
+
+// Manifest returns the chunks stored so far, the buffered bytes are not listed until they are flushed.
+func (w *PayloadWriter) Manifest() PayloadManifest {
+	return w.manifest
+}
+
+// setPayload stores the manifest of the payload with the SHA-256, the chunks must already be stored.
+// The manifest is written after its chunks, so it expires after them.
+func setPayload(ctx context.Context, s Storage, hash string, manifest PayloadManifest) error {
+	buf, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("fail to encode payload manifest, err: %w", err)
+	}
+	return s.SetValue(ctx, PayloadKey(hash), payloadManifestMarker+string(buf))
+}
+
+// getManifest returns the manifest of the payload with the SHA-256, false when it was stored whole.
+func getManifest(ctx context.Context, s Storage, hash string) (PayloadManifest, string, bool, error) {
+	value, err := s.GetValue(ctx, PayloadKey(hash))
+	if err != nil {
+		return PayloadManifest{}, "", false, err
+	}
+	if data, ok := strings.CutPrefix(value, payloadManifestMarker); ok {
+		var manifest PayloadManifest
+		if err := json.Unmarshal([]byte(data), &manifest); err == nil {
+			return manifest, "", true, nil
+		}
+	}
+	return PayloadManifest{}, value, false, nil
+}
+
+// Payload is a stored payload, its chunks are read when it is read.
+type Payload struct {
+	s        Storage
+	manifest PayloadManifest
+	// whole is the value of a payload stored before payloads were chunked
+	whole *string
+}
+
+// GetPayload returns the payload with the ID, or ErrNotFound. A payload with an ID of another algorithm than
+// SHA-256 is found through its alias.
+func GetPayload(ctx context.Context, s Storage, id PayloadID) (*Payload, error) {
+	hash, err := resolvePayload(ctx, s, id)
+	if err != nil {
+		return nil, err
+	}
+	manifest, value, ok, err := getManifest(ctx, s, hash)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &Payload{s: s, manifest: manifest}, nil
+	}
+	// the payload was stored whole, it is verified as it is already in memory
+	sum := sha256.Sum256([]byte(value))
+	if hex.EncodeToString(sum[:]) != hash {
+		return nil, ErrCorruptPayload
+	}
+	return &Payload{s: s, manifest: PayloadManifest{Size: int64(len(value))}, whole: &value}, nil
+}
+
+// Size returns the size of the payload in bytes.
+func (p *Payload) Size() int64 {
+	return p.manifest.Size
+}
+
+// NewReader returns a reader of the bytes [start, end) of the payload. The chunks are read one at a time and
+// verified against their hash, the first one before NewReader returns, so a missing or corrupt payload is
+// reported before anything is read.
+func (p *Payload) NewReader(ctx context.Context, start, end int64) (io.Reader, error) {
+	if start < 0 || end > p.Size() || start > end {
+		return nil, fmt.Errorf("range %d-%d is not within the %d bytes of the payload", start, end, p.Size())
+	}
+	if p.whole != nil {
+		return strings.NewReader((*p.whole)[start:end]), nil
+	}
+	r := &payloadReader{ctx: ctx, p: p, pos: start, end: end}
+	// the chunks before start are skipped without being read
+	for r.index < len(p.manifest.Chunks) && r.chunkStart+p.manifest.Chunks[r.index].Size <= start {
+		r.chunkStart += p.manifest.Chunks[r.index].Size
+		r.index++
+	}
+	if start < end {
+		if err := r.next(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// payloadReader reads the chunks of a payload from storage.
+type payloadReader struct {
+	ctx context.Context
+	p   *Payload
+	// index is the next chunk to read, it starts at chunkStart in the payload
+	index      int
+	chunkStart int64
+	pos, end   int64
+	// buf is what is left to read of the current chunk
+	buf string
+}
+
+func (r *payloadReader) Read(b []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.pos >= r.end {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	r.pos += int64(n)
+	return n, nil
+}
+
+// next reads and verifies the next chunk, and keeps its bytes within the range.
+func (r *payloadReader) next() error {
+	if r.index >= len(r.p.manifest.Chunks) {
+		return fmt.Errorf("payload has no chunk %d, err: %w", r.index, ErrCorruptPayload)
+	}
+	chunk := r.p.manifest.Chunks[r.index]
+	value, err := r.p.s.GetValue(r.ctx, PayloadChunkKey(r.p.manifest.ID, r.index))
+	if err != nil {
+		return fmt.Errorf("fail to read chunk %d, err: %w", r.index, err)
+	}
+	sum := sha256.Sum256([]byte(value))
+	if int64(len(value)) != chunk.Size || hex.EncodeToString(sum[:]) != chunk.Hash {
+		return fmt.Errorf("chunk %d does not match its hash, err: %w", r.index, ErrCorruptPayload)
+	}
+	r.buf = value[max(r.pos-r.chunkStart, 0):min(r.end-r.chunkStart, chunk.Size)]
+	r.chunkStart += chunk.Size
+	r.index++
+	return nil
+}
+
+// readChunks reads the chunks of manifest one at a time, verifies them and passes them to fn.
+func readChunks(ctx context.Context, s Storage, manifest PayloadManifest, fn func(key, value string) error) error {
+	for i, chunk := range manifest.Chunks {
+		key := PayloadChunkKey(manifest.ID, i)
+		value, err := s.GetValue(ctx, key)
+		if err != nil {
+			return fmt.Errorf("fail to read chunk %d, err: %w", i, err)
+		}
+		sum := sha256.Sum256([]byte(value))
+		if int64(len(value)) != chunk.Size || hex.EncodeToString(sum[:]) != chunk.Hash {
+			return fmt.Errorf("chunk %d does not match its hash, err: %w", i, ErrCorruptPayload)
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshChunks stores the chunks of manifest again, so they expire with the payload they are stored for.
+func refreshChunks(ctx context.Context, s Storage, manifest PayloadManifest) error {
+	return readChunks(ctx, s, manifest, func(key, value string) error {
+		if err := s.SetValue(ctx, key, value); err != nil {
+			return fmt.Errorf("fail to store chunk %s, err: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// CommitPayload verifies the chunks of manifest against the ID of the payload, then stores it with StorePayload.
+// The chunks are read one at a time and stored again, so they expire with the payload instead of with the upload
+// they were sent with. A payload that doesn't match the ID is reported with ErrCorruptPayload.
+func CommitPayload(ctx context.Context, s Storage, id PayloadID, manifest PayloadManifest, sessions []string) error {
+	canonical, h := sha256.New(), id.NewHash()
+	err := readChunks(ctx, s, manifest, func(key, value string) error {
+		_, _ = io.WriteString(canonical, value)
+		_, _ = io.WriteString(h, value)
+		if err := s.SetValue(ctx, key, value); err != nil {
+			return fmt.Errorf("fail to store chunk %s, err: %w", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if result := hex.EncodeToString(h.Sum(nil)); result != id.Digest {
+		return fmt.Errorf("chunks hash to %s, err: %w", result, ErrCorruptPayload)
+	}
+	return StorePayload(ctx, s, id, hex.EncodeToString(canonical.Sum(nil)), manifest, sessions)
+}
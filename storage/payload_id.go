@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"lukechampine.com/blake3"
+)
+
+// Hash algorithms of the payload IDs, named as in the multihash table.
+const (
+	HashSHA256    = "sha2-256"
+	HashSHA512256 = "sha2-512-256"
+	HashBLAKE3    = "blake3"
+)
+
+// payloadHashes returns a new hash of each algorithm, the digests are 32 bytes.
+var payloadHashes = map[string]func() hash.Hash{
+	HashSHA256:    sha256.New,
+	HashSHA512256: sha512.New512_256,
+	HashBLAKE3:    func() hash.Hash { return blake3.New(32, nil) },
+}
+
+// PayloadID identifies a payload by the digest of its content, written algorithm:hex such as blake3:9f86d0...
+// A bare hex digest is a SHA-256, the only algorithm before payloads supported several.
+type PayloadID struct {
+	Algorithm string
+	// Digest is hex encoded, in lower case
+	Digest string
+}
+
+// ParsePayloadID parses a payload ID and checks the digest has the size of the algorithm.
+func ParsePayloadID(s string) (PayloadID, error) {
+	algorithm, digest, found := strings.Cut(strings.TrimSpace(s), ":")
+	if !found {
+		algorithm, digest = HashSHA256, algorithm
+	}
+	newHash, ok := payloadHashes[algorithm]
+	if !ok {
+		return PayloadID{}, fmt.Errorf("unknown hash algorithm %s", algorithm)
+	}
+	buf, err := hex.DecodeString(digest)
+	if err != nil || strings.ToLower(digest) != digest {
+		return PayloadID{}, fmt.Errorf("digest %s is not lower case hex", digest)
+	}
+	if size := newHash().Size(); len(buf) != size {
+		return PayloadID{}, fmt.Errorf("%s digest has %d bytes instead of %d", algorithm, len(buf), size)
+	}
+	return PayloadID{Algorithm: algorithm, Digest: digest}, nil
+}
+
+// String returns the ID as it is parsed, a SHA-256 is written as a bare hex digest.
+func (id PayloadID) String() string {
+	if id.Algorithm == HashSHA256 {
+		return id.Digest
+	}
+	return id.Algorithm + ":" + id.Digest
+}
+
+// NewHash returns a hash of the algorithm of the ID.
+func (id PayloadID) NewHash() hash.Hash {
+	return payloadHashes[id.Algorithm]()
+}
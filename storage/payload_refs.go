@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// payloadPublic is the reader listed for a payload stored without a session, anyone can read it until it expires.
+const payloadPublic = ""
+
+// StorePayload stores the manifest of the payload with the ID, whose SHA-256 is hash, and lists the sessions as its
+// readers. Payloads are stored once under their SHA-256: when the content is already stored its chunks are kept
+// and the chunks of manifest are deleted, and an ID of another algorithm is stored as an alias of the SHA-256.
+// A payload stored for sessions only is deleted by ReleaseSessionPayloads once all of them are released.
+// The readers and the aliases are sets updated with atomic operations, so relays sharing a storage can store and
+// release the same payload concurrently. The readers are listed before the manifest is stored, so a payload is
+// never stored without its scope.
+func StorePayload(ctx context.Context, s Storage, id PayloadID, hash string, manifest PayloadManifest, sessions []string) error {
+	readers := slices.Clone(sessions)
+	if len(sessions) == 0 {
+		readers = append(readers, payloadPublic)
+	}
+	existing, _, chunked, err := getManifest(ctx, s, hash)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("fail to read payload %s, err: %w", hash, err)
+	default:
+		if !chunked || !existing.Scoped {
+			// the payload was stored before its readers were listed, it stays readable by anyone
+			readers = append(readers, payloadPublic)
+		}
+		// a stored payload with a missing or corrupt chunk is replaced
+		if chunked && existing.ID != manifest.ID && refreshChunks(ctx, s, existing) == nil {
+			// the new chunks expire anyway when they fail to be deleted
+			_ = deleteChunks(ctx, s, manifest)
+			manifest = existing
+		}
+	}
+	if err := s.AddSetMembers(ctx, PayloadReadersKey(hash), readers...); err != nil {
+		return fmt.Errorf("fail to store readers of payload %s, err: %w", hash, err)
+	}
+	aliases, err := s.GetSetMembers(ctx, PayloadAliasesKey(hash))
+	if err != nil {
+		return fmt.Errorf("fail to read aliases of payload %s, err: %w", hash, err)
+	}
+	if id.Algorithm != HashSHA256 && !slices.Contains(aliases, id.String()) {
+		aliases = append(aliases, id.String())
+	}
+	// the aliases are stored again so they expire with the payload
+	if len(aliases) > 0 {
+		if err := s.AddSetMembers(ctx, PayloadAliasesKey(hash), aliases...); err != nil {
+			return fmt.Errorf("fail to store aliases of payload %s, err: %w", hash, err)
+		}
+	}
+	for _, alias := range aliases {
+		if err := s.SetValue(ctx, PayloadAliasKey(alias), hash); err != nil {
+			return fmt.Errorf("fail to store payload alias %s, err: %w", alias, err)
+		}
+	}
+	manifest.Scoped = true
+	if err := setPayload(ctx, s, hash, manifest); err != nil {
+		return fmt.Errorf("fail to store payload %s, err: %w", hash, err)
+	}
+	for _, sessionID := range sessions {
+		if err := addSessionPayload(ctx, s, sessionID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseSessionPayloads removes the session from the readers of its payloads, and deletes the payloads that have
+// no reader left. It is called before the session is deleted. Removing a reader and counting the ones left is a
+// single atomic operation, so only one relay deletes a payload. A payload stored again while it is deleted can be
+// deleted with it, it is then not found until it is uploaded again.
+func ReleaseSessionPayloads(ctx context.Context, s Storage, sessionID string) error {
+	hashes, err := s.GetSetMembers(ctx, SessionPayloadsKey(sessionID))
+	if err != nil {
+		return fmt.Errorf("fail to read payloads of session %s, err: %w", sessionID, err)
+	}
+	for _, hash := range hashes {
+		left, err := s.RemoveSetMember(ctx, PayloadReadersKey(hash), sessionID)
+		if err != nil {
+			return fmt.Errorf("fail to release payload %s, err: %w", hash, err)
+		}
+		if left == 0 {
+			if err := deletePayload(ctx, s, hash); err != nil {
+				return err
+			}
+		}
+	}
+	return s.DeleteValue(ctx, SessionPayloadsKey(sessionID))
+}
+
+// PayloadScope returns the sessions that can read the payload with the ID, none when anyone can read it: a payload
+// is scoped to sessions when it was only stored for sessions, and not stored before its readers were listed.
+// It fails closed, a scoped payload whose readers can't be read or have all been released is reported as not found.
+func PayloadScope(ctx context.Context, s Storage, id PayloadID) ([]string, error) {
+	hash, err := resolvePayload(ctx, s, id)
+	if err != nil {
+		return nil, err
+	}
+	manifest, _, chunked, err := getManifest(ctx, s, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !chunked || !manifest.Scoped {
+		return nil, nil
+	}
+	readers, err := s.GetSetMembers(ctx, PayloadReadersKey(hash))
+	if err != nil {
+		return nil, fmt.Errorf("fail to read readers of payload %s, err: %w", hash, err)
+	}
+	if slices.Contains(readers, payloadPublic) {
+		return nil, nil
+	}
+	if len(readers) == 0 {
+		return nil, fmt.Errorf("payload %s has no reader, err: %w", hash, ErrNotFound)
+	}
+	return readers, nil
+}
+
+// resolvePayload returns the SHA-256 of the payload with the ID.
+func resolvePayload(ctx context.Context, s Storage, id PayloadID) (string, error) {
+	if id.Algorithm == HashSHA256 {
+		return id.Digest, nil
+	}
+	return s.GetValue(ctx, PayloadAliasKey(id.String()))
+}
+
+// deletePayload deletes the manifest, the chunks and the aliases of a payload. The manifest is deleted first, so the
+// payload is not found while the rest is deleted.
+func deletePayload(ctx context.Context, s Storage, hash string) error {
+	manifest, _, chunked, err := getManifest(ctx, s, hash)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("fail to read payload %s, err: %w", hash, err)
+	}
+	if err := s.DeleteValue(ctx, PayloadKey(hash)); err != nil {
+		return fmt.Errorf("fail to delete payload %s, err: %w", hash, err)
+	}
+	if chunked {
+		if err := deleteChunks(ctx, s, manifest); err != nil {
+			return err
+		}
+	}
+	aliases, err := s.GetSetMembers(ctx, PayloadAliasesKey(hash))
+	if err != nil {
+		return fmt.Errorf("fail to read aliases of payload %s, err: %w", hash, err)
+	}
+	keys := []string{PayloadAliasesKey(hash)}
+	for _, alias := range aliases {
+		keys = append(keys, PayloadAliasKey(alias))
+	}
+	for _, key := range keys {
+		if err := s.DeleteValue(ctx, key); err != nil {
+			return fmt.Errorf("fail to delete payload %s, err: %w", hash, err)
+		}
+	}
+	return nil
+}
+
+func deleteChunks(ctx context.Context, s Storage, manifest PayloadManifest) error {
+	for i := range manifest.Chunks {
+		if err := s.DeleteValue(ctx, PayloadChunkKey(manifest.ID, i)); err != nil {
+			return fmt.Errorf("fail to delete chunk %d, err: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// addSessionPayload adds the payload to the set of the session, the set is deleted with the session.
+func addSessionPayload(ctx context.Context, s Storage, sessionID, hash string) error {
+	key := SessionPayloadsKey(sessionID)
+	if err := s.AddSetMembers(ctx, key, hash); err != nil {
+		return fmt.Errorf("fail to add payload %s to session %s, err: %w", hash, sessionID, err)
+	}
+	return s.AddSessionKey(ctx, sessionID, key)
+}
@@ -0,0 +1,797 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/contexthelper"
+	"github.com/vultisig/vultisig-relay/model"
+)
+
+var _ Storage = (*SQLStorage)(nil)
+
+// sqlCleanupInterval is how often expired rows are removed.
+const sqlCleanupInterval = time.Minute
+
+// key kinds, an index removes the keys it tracks when it expires
+const (
+	sqlKindList     = "list"
+	sqlKindMessages = "messages"
+	sqlKindValue    = "value"
+	sqlKindIndex    = "index"
+	sqlKindSet      = "set"
+)
+
+// sqlDialect holds what differs between the supported databases.
+type sqlDialect struct {
+	name          string
+	driver        string
+	autoIncrement string
+	blob          string
+	// numbered placeholders ($1) instead of ?
+	numbered bool
+	// lockMigrations is held by the migration transaction until it ends, so relays starting together apply the
+	// migrations once, sqlite has a single writer and needs no lock
+	lockMigrations string
+}
+
+// sqlMigrationLock is the key of the postgres advisory lock taken while migrating, "relay" in ASCII.
+const sqlMigrationLock = 0x72656c6179
+
+var (
+	dialectSQLite = sqlDialect{
+		name:          "sqlite",
+		driver:        "sqlite",
+		autoIncrement: "INTEGER PRIMARY KEY AUTOINCREMENT",
+		blob:          "BLOB",
+	}
+	dialectPostgres = sqlDialect{
+		name:           "postgres",
+		driver:         "pgx",
+		autoIncrement:  "BIGSERIAL PRIMARY KEY",
+		blob:           "BYTEA",
+		numbered:       true,
+		lockMigrations: fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", sqlMigrationLock),
+	}
+)
+
+// sqlMigrations are applied in order, each one exactly once, a migration must never change once released.
+var sqlMigrations = []func(d sqlDialect) []string{
+	func(d sqlDialect) []string {
+		return []string{
+			`CREATE TABLE relay_keys (
+				key TEXT PRIMARY KEY,
+				kind TEXT NOT NULL,
+				expires_at BIGINT NOT NULL
+			)`,
+			`CREATE INDEX relay_keys_expires_at ON relay_keys (expires_at)`,
+			fmt.Sprintf(`CREATE TABLE relay_list_items (
+				id %s,
+				key TEXT NOT NULL,
+				item TEXT NOT NULL,
+				UNIQUE (key, item)
+			)`, d.autoIncrement),
+			fmt.Sprintf(`CREATE TABLE relay_messages (
+				id %s,
+				key TEXT NOT NULL,
+				hash TEXT NOT NULL,
+				message TEXT NOT NULL,
+				UNIQUE (key, hash)
+			)`, d.autoIncrement),
+			fmt.Sprintf(`CREATE TABLE relay_values (
+				key TEXT PRIMARY KEY,
+				value %s NOT NULL
+			)`, d.blob),
+		}
+	},
+	func(d sqlDialect) []string {
+		return []string{
+			fmt.Sprintf(`CREATE TABLE relay_users (
+				id %s,
+				api_key TEXT NOT NULL,
+				created_at BIGINT,
+				expired_at BIGINT,
+				no_of_vaults BIGINT NOT NULL,
+				is_paid BOOLEAN NOT NULL
+			)`, d.autoIncrement),
+		}
+	},
+}
+
+// sqlDataTables are the tables that hold the data of a key.
+var sqlDataTables = []string{"relay_list_items", "relay_messages", "relay_values"}
+
+// SQLStorage is a storage backed by a SQL database, SQLite or Postgres compatible,
+// for operators who already run a database and want durable storage.
+type SQLStorage struct {
+	db                *sql.DB
+	dialect           sqlDialect
+	defaultExpiration time.Duration
+	defaultUserExpire time.Duration
+	stop              chan struct{}
+	wg                sync.WaitGroup
+}
+
+// parseConnectionString returns the dialect and the driver data source name of a connection string,
+// postgres://... or postgresql://... for Postgres, sqlite://path or sqlite://:memory: for SQLite.
+func parseConnectionString(connectionString string) (sqlDialect, string, error) {
+	switch {
+	case strings.HasPrefix(connectionString, "postgres://"), strings.HasPrefix(connectionString, "postgresql://"):
+		return dialectPostgres, connectionString, nil
+	case strings.HasPrefix(connectionString, "sqlite://"):
+		return dialectSQLite, strings.TrimPrefix(connectionString, "sqlite://"), nil
+	default:
+		return sqlDialect{}, "", fmt.Errorf("unsupported connection string, expected postgres:// or sqlite://")
+	}
+}
+
+// NewSQLStorage connects to the database, applies pending migrations and starts the cleanup job.
+func NewSQLStorage(connectionString string, expiration config.Expiration) (*SQLStorage, error) {
+	dialect, dsn, err := parseConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(dialect.driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open %s database, err: %w", dialect.name, err)
+	}
+	if dialect == dialectSQLite {
+		// sqlite allows a single writer, serialize access instead of failing with busy errors
+		db.SetMaxOpenConns(1)
+	}
+	s := &SQLStorage{
+		db:                db,
+		dialect:           dialect,
+		defaultExpiration: expiration.SessionTTL(),
+		defaultUserExpire: expiration.ValueTTL(),
+		stop:              make(chan struct{}),
+	}
+	if err := s.migrate(context.Background()); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	s.wg.Add(1)
+	go s.cleanupLoop()
+	return s, nil
+}
+
+// q rewrites ? placeholders for the dialect.
+func (s *SQLStorage) q(query string) string {
+	if !s.dialect.numbered {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// migrate applies the pending migrations in a single transaction, which holds the migration lock of the dialect,
+// a relay that waited for the lock finds the migrations applied.
+func (s *SQLStorage) migrate(ctx context.Context) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if s.dialect.lockMigrations != "" {
+			if _, err := tx.ExecContext(ctx, s.dialect.lockMigrations); err != nil {
+				return fmt.Errorf("fail to lock migrations, err: %w", err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS relay_schema_migrations (
+			version BIGINT PRIMARY KEY,
+			applied_at BIGINT NOT NULL
+		)`); err != nil {
+			return fmt.Errorf("fail to create migrations table, err: %w", err)
+		}
+		var current int
+		if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM relay_schema_migrations`).Scan(&current); err != nil {
+			return fmt.Errorf("fail to get schema version, err: %w", err)
+		}
+		for i := current; i < len(sqlMigrations); i++ {
+			version := i + 1
+			for _, stmt := range sqlMigrations[i](s.dialect) {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return fmt.Errorf("fail to apply migration %d, err: %w", version, err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, s.q(`INSERT INTO relay_schema_migrations (version, applied_at) VALUES (?, ?)`), version, time.Now().Unix()); err != nil {
+				return fmt.Errorf("fail to apply migration %d, err: %w", version, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStorage) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// live returns a condition on relay_keys that only matches keys that have not expired.
+func (s *SQLStorage) live(key string) (string, []interface{}) {
+	return `EXISTS (SELECT 1 FROM relay_keys k WHERE k.key = ? AND k.expires_at > ?)`, []interface{}{key, time.Now().UnixNano()}
+}
+
+// touch creates or refreshes a key, the data left by an expired key that was not cleaned up yet is removed first.
+func (s *SQLStorage) touch(ctx context.Context, tx *sql.Tx, key, kind string, ttl time.Duration) error {
+	var expiresAt int64
+	err := tx.QueryRowContext(ctx, s.q(`SELECT expires_at FROM relay_keys WHERE key = ?`), key).Scan(&expiresAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case expiresAt <= time.Now().UnixNano():
+		if err := s.deleteKeys(ctx, tx, []string{key}); err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, s.q(`INSERT INTO relay_keys (key, kind, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET kind = excluded.kind, expires_at = excluded.expires_at`),
+		key, kind, time.Now().Add(ttl).UnixNano())
+	return err
+}
+
+// deleteKeys removes the given keys and all their data.
+func (s *SQLStorage) deleteKeys(ctx context.Context, tx *sql.Tx, keys []string) error {
+	for _, key := range keys {
+		for _, table := range append([]string{"relay_keys"}, sqlDataTables...) {
+			if _, err := tx.ExecContext(ctx, s.q(fmt.Sprintf(`DELETE FROM %s WHERE key = ?`, table)), key); err != nil {
+				return fmt.Errorf("fail to delete %s from %s, err: %w", key, table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SQLStorage) cleanupLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(sqlCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			_ = s.cleanup(context.Background())
+		}
+	}
+}
+
+// cleanup removes every expired key, expired session indexes also remove the keys they track.
+func (s *SQLStorage) cleanup(ctx context.Context) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now().UnixNano()
+		for _, table := range sqlDataTables {
+			_, err := tx.ExecContext(ctx, s.q(fmt.Sprintf(`DELETE FROM %s WHERE key IN (
+				SELECT i.item FROM relay_list_items i JOIN relay_keys k ON k.key = i.key
+				WHERE k.kind = ? AND k.expires_at <= ?)`, table)), sqlKindIndex, now)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, s.q(`DELETE FROM relay_keys WHERE key IN (
+			SELECT i.item FROM relay_list_items i JOIN relay_keys k ON k.key = i.key
+			WHERE k.kind = ? AND k.expires_at <= ?)`), sqlKindIndex, now)
+		if err != nil {
+			return err
+		}
+		for _, table := range sqlDataTables {
+			_, err := tx.ExecContext(ctx, s.q(fmt.Sprintf(`DELETE FROM %s WHERE key IN (
+				SELECT key FROM relay_keys WHERE expires_at <= ?)`, table)), now)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx, s.q(`DELETE FROM relay_keys WHERE expires_at <= ?`), now)
+		return err
+	})
+}
+
+// addListItems appends the items that are not in the list yet.
+func (s *SQLStorage) addListItems(ctx context.Context, tx *sql.Tx, key string, items []string) error {
+	for _, item := range items {
+		_, err := tx.ExecContext(ctx, s.q(`INSERT INTO relay_list_items (key, item) VALUES (?, ?) ON CONFLICT (key, item) DO NOTHING`), key, item)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx.
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getList returns the items of a list in insertion order.
+func (s *SQLStorage) getList(ctx context.Context, q sqlQuerier, key string) ([]string, error) {
+	cond, args := s.live(key)
+	rows, err := q.QueryContext(ctx, s.q(`SELECT item FROM relay_list_items WHERE key = ? AND `+cond+` ORDER BY id`), append([]interface{}{key}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	items := []string{}
+	for rows.Next() {
+		var item string
+		if err := rows.Scan(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *SQLStorage) SetSession(ctx context.Context, key string, participants []string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touch(ctx, tx, key, sqlKindList, s.defaultExpiration); err != nil {
+			return err
+		}
+		return s.addListItems(ctx, tx, key, participants)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to set session %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// GetSession gets a session with a list of participants, a missing session has no participants.
+func (s *SQLStorage) GetSession(ctx context.Context, key string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	participants, err := s.getList(ctx, s.db, key)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get session %s, err: %w", key, err)
+	}
+	return participants, nil
+}
+
+// DeleteSession deletes a session together with every key recorded in its session index.
+func (s *SQLStorage) DeleteSession(ctx context.Context, sessionID string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		indexKey := SessionIndexKey(sessionID)
+		keys, err := s.getList(ctx, tx, indexKey)
+		if err != nil {
+			return err
+		}
+		return s.deleteKeys(ctx, tx, append(keys, SessionKey(sessionID), indexKey))
+	})
+	if err != nil {
+		return fmt.Errorf("fail to delete session %s, err: %w", sessionID, err)
+	}
+	return nil
+}
+
+// AddSessionKey records the given key in the session index, so it will be removed when the session is deleted.
+func (s *SQLStorage) AddSessionKey(ctx context.Context, sessionID string, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		indexKey := SessionIndexKey(sessionID)
+		if err := s.touch(ctx, tx, indexKey, sqlKindIndex, s.defaultExpiration); err != nil {
+			return err
+		}
+		if err := s.addListItems(ctx, tx, indexKey, []string{key}); err != nil {
+			return err
+		}
+		// the tracked keys expire with the index, so they are not read once the session expired
+		now := time.Now()
+		_, err := tx.ExecContext(ctx, s.q(`UPDATE relay_keys SET expires_at = ? WHERE expires_at > ? AND key IN (
+			SELECT item FROM relay_list_items WHERE key = ?)`), now.Add(s.defaultExpiration).UnixNano(), now.UnixNano(), indexKey)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fail to add key %s to session index %s, err: %w", key, sessionID, err)
+	}
+	return nil
+}
+
+// GetMessages gets the messages stored under key, in the order they were added.
+func (s *SQLStorage) GetMessages(ctx context.Context, key string) ([]model.Message, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	cond, args := s.live(key)
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT message FROM relay_messages WHERE key = ? AND `+cond+` ORDER BY id`), append([]interface{}{key}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get messages %s, err: %w", key, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	messages := []model.Message{}
+	for rows.Next() {
+		var item string
+		if err := rows.Scan(&item); err != nil {
+			return nil, fmt.Errorf("fail to get messages %s, err: %w", key, err)
+		}
+		var message model.Message
+		if err := json.Unmarshal([]byte(item), &message); err != nil {
+			return nil, fmt.Errorf("fail to unmarshal message, err: %w", err)
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("fail to get messages %s, err: %w", key, err)
+	}
+	return messages, nil
+}
+
+// SetMessage adds a message, the unique (key, hash) constraint stores a message with the same hash only once.
+func (s *SQLStorage) SetMessage(ctx context.Context, key string, message model.Message) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	buf, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("fail to marshal message, err: %w", err)
+	}
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touch(ctx, tx, key, sqlKindMessages, s.defaultExpiration); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, s.q(`INSERT INTO relay_messages (key, hash, message) VALUES (?, ?, ?) ON CONFLICT (key, hash) DO NOTHING`),
+			key, message.Hash, string(buf))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fail to set message, err: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessages deletes all the messages stored under key.
+func (s *SQLStorage) DeleteMessages(ctx context.Context, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	if err := s.inTx(ctx, func(tx *sql.Tx) error { return s.deleteKeys(ctx, tx, []string{key}) }); err != nil {
+		return fmt.Errorf("fail to delete messages, err: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessage deletes a message in the given key with hash equals to the given hash
+func (s *SQLStorage) DeleteMessage(ctx context.Context, key string, hash string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, s.q(`DELETE FROM relay_messages WHERE key = ? AND hash = ?`), key, hash)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		return s.touch(ctx, tx, key, sqlKindMessages, s.defaultExpiration)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to delete message, err: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStorage) SetValue(ctx context.Context, key string, value string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touch(ctx, tx, key, sqlKindValue, s.defaultUserExpire); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, s.q(`INSERT INTO relay_values (key, value) VALUES (?, ?)
+			ON CONFLICT (key) DO UPDATE SET value = excluded.value`), key, []byte(value))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fail to set value %s, err: %w", key, err)
+	}
+	return nil
+}
+
+func (s *SQLStorage) GetValue(ctx context.Context, key string) (string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return "", ctx.Err()
+	}
+	cond, args := s.live(key)
+	var value []byte
+	err := s.db.QueryRowContext(ctx, s.q(`SELECT value FROM relay_values WHERE key = ? AND `+cond), append([]interface{}{key}, args...)...).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("fail to get value %s, err: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("fail to get value %s, err: %w", key, err)
+	}
+	return string(value), nil
+}
+
+func (s *SQLStorage) DeleteValue(ctx context.Context, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	if err := s.inTx(ctx, func(tx *sql.Tx) error { return s.deleteKeys(ctx, tx, []string{key}) }); err != nil {
+		return fmt.Errorf("fail to delete value %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// AddSetMembers adds the members to the set stored under key, and refreshes its expiration.
+func (s *SQLStorage) AddSetMembers(ctx context.Context, key string, members ...string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := s.touch(ctx, tx, key, sqlKindSet, s.defaultUserExpire); err != nil {
+			return err
+		}
+		return s.addListItems(ctx, tx, key, members)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to add members to set %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// RemoveSetMember removes the member and counts the members left in the same transaction.
+func (s *SQLStorage) RemoveSetMember(ctx context.Context, key string, member string) (int, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	left := 0
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// touching the key first locks its row, so concurrent removals count the members one after the other
+		if err := s.touch(ctx, tx, key, sqlKindSet, s.defaultUserExpire); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.q(`DELETE FROM relay_list_items WHERE key = ? AND item = ?`), key, member); err != nil {
+			return err
+		}
+		members, err := s.getList(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		left = len(members)
+		if left == 0 {
+			return s.deleteKeys(ctx, tx, []string{key})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fail to remove member from set %s, err: %w", key, err)
+	}
+	return left, nil
+}
+
+func (s *SQLStorage) GetSetMembers(ctx context.Context, key string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	members, err := s.getList(ctx, s.db, key)
+	if err != nil {
+		return nil, fmt.Errorf("fail to get set %s, err: %w", key, err)
+	}
+	return members, nil
+}
+
+func (s *SQLStorage) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("fail to ping %s database, err: %w", s.dialect.name, err)
+	}
+	return nil
+}
+
+func (s *SQLStorage) Type() string {
+	return TypeSQL
+}
+
+// Close stops the cleanup job and closes the database connections.
+func (s *SQLStorage) Close() error {
+	close(s.stop)
+	s.wg.Wait()
+	return s.db.Close()
+}
+
+var _ Inspector = (*SQLStorage)(nil)
+
+func (s *SQLStorage) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	// substr instead of LIKE, keys contain % once their segments are escaped
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT key FROM relay_keys WHERE substr(key, 1, ?) = ? AND expires_at > ? ORDER BY key`),
+		len(prefix), prefix, time.Now().UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("fail to list keys %s, err: %w", prefix, err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("fail to list keys %s, err: %w", prefix, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	var expiresAt int64
+	err := s.db.QueryRowContext(ctx, s.q(`SELECT expires_at FROM relay_keys WHERE key = ? AND expires_at > ?`), key, time.Now().UnixNano()).Scan(&expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("fail to get ttl of %s, err: %w", key, ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("fail to get ttl of %s, err: %w", key, err)
+	}
+	return time.Until(time.Unix(0, expiresAt)), nil
+}
+
+func (s *SQLStorage) SessionKeys(ctx context.Context, sessionID string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	keys, err := s.getList(ctx, s.db, SessionIndexKey(sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("fail to get keys of session %s, err: %w", sessionID, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+var _ UserStore = (*SQLStorage)(nil)
+
+// sqlUserColumns are the columns of relay_users, in the order scanUser reads them.
+const sqlUserColumns = `id, api_key, created_at, expired_at, no_of_vaults, is_paid`
+
+// nullTime stores a sql.NullTime as unix nanoseconds.
+func nullTime(t sql.NullTime) sql.NullInt64 {
+	if !t.Valid {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.Time.UnixNano(), Valid: true}
+}
+
+func fromNullTime(v sql.NullInt64) sql.NullTime {
+	if !v.Valid {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.Unix(0, v.Int64).UTC(), Valid: true}
+}
+
+type sqlScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row sqlScanner) (model.User, error) {
+	var u model.User
+	var createdAt, expiredAt sql.NullInt64
+	if err := row.Scan(&u.ID, &u.APIKey, &createdAt, &expiredAt, &u.NoOfVaults, &u.IsPaid); err != nil {
+		return model.User{}, err
+	}
+	u.CreatedAt = fromNullTime(createdAt)
+	u.ExpiredAt = fromNullTime(expiredAt)
+	return u, nil
+}
+
+func (s *SQLStorage) ListUsers(ctx context.Context, afterID int64, limit int) ([]model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT `+sqlUserColumns+` FROM relay_users WHERE id > ? ORDER BY id LIMIT ?`), afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("fail to list users, err: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	users := []model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("fail to list users, err: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *SQLStorage) GetUser(ctx context.Context, id int64) (model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return model.User{}, ctx.Err()
+	}
+	u, err := scanUser(s.db.QueryRowContext(ctx, s.q(`SELECT `+sqlUserColumns+` FROM relay_users WHERE id = ?`), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.User{}, fmt.Errorf("fail to get user %d, err: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return model.User{}, fmt.Errorf("fail to get user %d, err: %w", id, err)
+	}
+	return u, nil
+}
+
+func (s *SQLStorage) SaveUser(ctx context.Context, user *model.User) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	args := []interface{}{user.APIKey, nullTime(user.CreatedAt), nullTime(user.ExpiredAt), user.NoOfVaults, user.IsPaid}
+	if user.ID == 0 {
+		err := s.db.QueryRowContext(ctx, s.q(`INSERT INTO relay_users (api_key, created_at, expired_at, no_of_vaults, is_paid)
+			VALUES (?, ?, ?, ?, ?) RETURNING id`), args...).Scan(&user.ID)
+		if err != nil {
+			return fmt.Errorf("fail to create user, err: %w", err)
+		}
+		return nil
+	}
+	result, err := s.db.ExecContext(ctx, s.q(`UPDATE relay_users SET api_key = ?, created_at = ?, expired_at = ?, no_of_vaults = ?, is_paid = ?
+		WHERE id = ?`), append(args, user.ID)...)
+	if err != nil {
+		return fmt.Errorf("fail to save user %d, err: %w", user.ID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fail to save user %d, err: %w", user.ID, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("fail to save user %d, err: %w", user.ID, ErrNotFound)
+	}
+	return nil
+}
+
+func (s *SQLStorage) DeleteUser(ctx context.Context, id int64) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	result, err := s.db.ExecContext(ctx, s.q(`DELETE FROM relay_users WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("fail to delete user %d, err: %w", id, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("fail to delete user %d, err: %w", id, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("fail to delete user %d, err: %w", id, ErrNotFound)
+	}
+	return nil
+}
@@ -2,9 +2,16 @@ package storage
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -16,36 +23,60 @@ import (
 
 var ErrNotFound = errors.New("not found")
 
+// Storage backend types
+const (
+	TypeRedis  = "redis"
+	TypeMemory = "memory"
+	TypeBolt   = "bolt"
+	TypeSQL    = "sql"
+)
+
 // Storage is an interface that defines the methods to be implemented by a storage.
 type Storage interface {
 	SetSession(ctx context.Context, key string, participants []string) error
 	GetSession(ctx context.Context, key string) ([]string, error)
-	DeleteSession(ctx context.Context, key string) error
+	// DeleteSession deletes a session together with every key recorded in its session index
+	DeleteSession(ctx context.Context, sessionID string) error
+	// AddSessionKey records a key in the session index once the key is written. The index expires like a session,
+	// every call refreshes it together with the keys it tracks, so they are removed when the session expires.
+	AddSessionKey(ctx context.Context, sessionID string, key string) error
 	GetMessages(ctx context.Context, key string) ([]model.Message, error)
 	SetMessage(ctx context.Context, key string, message model.Message) error
 	DeleteMessages(ctx context.Context, key string) error
 	DeleteMessage(ctx context.Context, key string, hash string) error
 	SetValue(ctx context.Context, key string, value string) error
 	GetValue(ctx context.Context, key string) (string, error)
+	// DeleteValue deletes a value or a set, deleting a missing value succeeds
+	DeleteValue(ctx context.Context, key string) error
+	// AddSetMembers adds the members to the set stored under key, the set lives as long as a value
+	AddSetMembers(ctx context.Context, key string, members ...string) error
+	// RemoveSetMember removes the member from the set and returns how many are left, as one atomic operation.
+	// The set is deleted once it is empty.
+	RemoveSetMember(ctx context.Context, key string, member string) (int, error)
+	// GetSetMembers returns the members of the set in no particular order, none when it doesn't exist
+	GetSetMembers(ctx context.Context, key string) ([]string, error)
+	// Ping checks that the storage backend is reachable
+	Ping(ctx context.Context) error
+	// Type returns the backend type, such as redis
+	Type() string
+	Close() error
 }
 
 var _ Storage = (*RedisStorage)(nil)
 
 type RedisStorage struct {
 	cfg               config.RedisServer
-	client            *redis.Client
+	client            redis.UniversalClient
 	defaultExpiration time.Duration
 	defaultUserExpire time.Duration
 }
 
-// NewRedisStorage returns a new storage that use redis
-func NewRedisStorage(cfg config.RedisServer) (*RedisStorage, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr,
-		Username: cfg.User,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+// NewRedisStorage returns a new storage that use redis, in standalone, sentinel or cluster mode.
+func NewRedisStorage(cfg config.RedisServer, expiration config.Expiration) (*RedisStorage, error) {
+	client, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
 	status := client.Ping(context.Background())
 	if status.Err() != nil {
 		return nil, status.Err()
@@ -53,11 +84,81 @@ func NewRedisStorage(cfg config.RedisServer) (*RedisStorage, error) {
 	return &RedisStorage{
 		cfg:               cfg,
 		client:            client,
-		defaultExpiration: time.Minute * 5,
-		defaultUserExpire: time.Hour,
+		defaultExpiration: expiration.SessionTTL(),
+		defaultUserExpire: expiration.ValueTTL(),
 	}, nil
 }
 
+func newRedisClient(cfg config.RedisServer) (redis.UniversalClient, error) {
+	addrs := cfg.Addrs
+	if len(addrs) == 0 && cfg.Addr != "" {
+		addrs = []string{cfg.Addr}
+	}
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		Username:         cfg.User,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		MasterName:       cfg.MasterName,
+		SentinelUsername: cfg.SentinelUser,
+		SentinelPassword: cfg.SentinelPassword,
+	}
+	if cfg.TLS.Enabled {
+		tlsConfig, err := newRedisTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("fail to create redis tls config, err: %w", err)
+		}
+		opts.TLSConfig = tlsConfig
+	}
+	switch cfg.Mode {
+	case "", config.RedisModeStandalone:
+		return redis.NewClient(opts.Simple()), nil
+	case config.RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("master name is required in redis sentinel mode")
+		}
+		return redis.NewFailoverClient(opts.Failover()), nil
+	case config.RedisModeCluster:
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("redis cluster only supports db 0, got %d", cfg.DB)
+		}
+		return redis.NewClusterClient(opts.Cluster()), nil
+	default:
+		return nil, fmt.Errorf("unknown redis mode %s", cfg.Mode)
+	}
+}
+
+func newRedisTLSConfig(cfg config.RedisTLS) (*tls.Config, error) {
+	minVersion, err := config.ParseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         minVersion,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		buf, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("fail to read ca file %s, err: %w", cfg.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return nil, fmt.Errorf("no certificate found in ca file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("fail to load client certificate, err: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
 // SetSession sets a session with a list of participants.
 func (s *RedisStorage) SetSession(ctx context.Context, key string, participants []string) error {
 	if contexthelper.CheckCancellation(ctx) != nil {
@@ -81,8 +182,10 @@ func (s *RedisStorage) SetSession(ctx context.Context, key string, participants
 			participantsToAdd = append(participantsToAdd, p)
 		}
 	}
-	if result := s.client.RPush(ctx, key, participantsToAdd); result.Err() != nil {
-		return fmt.Errorf("fail to set session %s, err: %w", key, result.Err())
+	if len(participantsToAdd) > 0 {
+		if result := s.client.RPush(ctx, key, participantsToAdd); result.Err() != nil {
+			return fmt.Errorf("fail to set session %s, err: %w", key, result.Err())
+		}
 	}
 	if result := s.client.Expire(ctx, key, s.defaultExpiration); result.Err() != nil {
 		return fmt.Errorf("fail to set expiration, err: %w", result.Err())
@@ -90,7 +193,7 @@ func (s *RedisStorage) SetSession(ctx context.Context, key string, participants
 	return nil
 }
 
-// GetSession gets a session with a list of participants.
+// GetSession gets a session with a list of participants, a missing session has no participants.
 func (s *RedisStorage) GetSession(ctx context.Context, key string) ([]string, error) {
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return nil, ctx.Err()
@@ -103,14 +206,45 @@ func (s *RedisStorage) GetSession(ctx context.Context, key string) ([]string, er
 
 }
 
-// DeleteSession deletes a session.
-func (s *RedisStorage) DeleteSession(ctx context.Context, key string) error {
+// DeleteSession deletes a session together with every key recorded in its session index.
+func (s *RedisStorage) DeleteSession(ctx context.Context, sessionID string) error {
 	if contexthelper.CheckCancellation(ctx) != nil {
 		return ctx.Err()
 	}
-	result := s.client.Del(ctx, key)
+	indexKey := SessionIndexKey(sessionID)
+	keys, err := s.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return fmt.Errorf("fail to get session index %s, err: %w", sessionID, err)
+	}
+	keys = append(keys, SessionKey(sessionID), indexKey)
+	result := s.client.Del(ctx, keys...)
 	if result.Err() != nil {
-		return fmt.Errorf("fail to delete session %s, err: %w", key, result.Err())
+		return fmt.Errorf("fail to delete session %s, err: %w", sessionID, result.Err())
+	}
+	return nil
+}
+
+// AddSessionKey records the given key in the session index, so it will be removed when the session is deleted.
+// Redis doesn't cascade expirations, so the tracked keys are given the expiration of the index instead.
+func (s *RedisStorage) AddSessionKey(ctx context.Context, sessionID string, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	indexKey := SessionIndexKey(sessionID)
+	keys, err := s.client.SMembers(ctx, indexKey).Result()
+	if err != nil {
+		return fmt.Errorf("fail to get session index %s, err: %w", sessionID, err)
+	}
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, indexKey, key)
+		pipe.Expire(ctx, indexKey, s.defaultExpiration)
+		for _, k := range append(keys, key) {
+			pipe.Expire(ctx, k, s.defaultExpiration)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to add key %s to session index %s, err: %w", key, sessionID, err)
 	}
 	return nil
 }
@@ -125,7 +259,7 @@ func (s *RedisStorage) GetMessages(ctx context.Context, key string) ([]model.Mes
 		return nil, fmt.Errorf("fail to get messages %s, err: %w", key, err)
 	}
 
-	var messages []model.Message
+	messages := make([]model.Message, 0, len(result))
 	for _, item := range result {
 		var message model.Message
 		if err := json.Unmarshal([]byte(item), &message); err != nil {
@@ -225,12 +359,248 @@ func (s *RedisStorage) GetValue(ctx context.Context, key string) (string, error)
 		return "", ctx.Err()
 	}
 	result, err := s.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("fail to get value %s, err: %w", key, ErrNotFound)
+	}
 	if err != nil {
 		return "", fmt.Errorf("fail to get value %s, err: %w", key, err)
 	}
 	return result, nil
 }
 
+func (s *RedisStorage) DeleteValue(ctx context.Context, key string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	if status := s.client.Del(ctx, key); status.Err() != nil {
+		return fmt.Errorf("fail to delete value %s, err: %w", key, status.Err())
+	}
+	return nil
+}
+
+// AddSetMembers adds the members to the set stored under key, and refreshes its expiration.
+func (s *RedisStorage) AddSetMembers(ctx context.Context, key string, members ...string) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, key, members)
+		pipe.Expire(ctx, key, s.defaultUserExpire)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to add members to set %s, err: %w", key, err)
+	}
+	return nil
+}
+
+// RemoveSetMember removes the member and counts the members left in the same transaction, redis deletes empty sets.
+func (s *RedisStorage) RemoveSetMember(ctx context.Context, key string, member string) (int, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	var card *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SRem(ctx, key, member)
+		pipe.Expire(ctx, key, s.defaultUserExpire)
+		card = pipe.SCard(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fail to remove member from set %s, err: %w", key, err)
+	}
+	return int(card.Val()), nil
+}
+
+func (s *RedisStorage) GetSetMembers(ctx context.Context, key string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	members, err := s.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fail to get set %s, err: %w", key, err)
+	}
+	return members, nil
+}
+
+func (s *RedisStorage) Ping(ctx context.Context) error {
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("fail to ping redis, err: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStorage) Type() string {
+	return TypeRedis
+}
+
 func (s *RedisStorage) Close() error {
 	return s.client.Close()
 }
+
+var _ Inspector = (*RedisStorage)(nil)
+
+// redisGlobEscaper escapes the characters that are special in redis match patterns.
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Keys scans the keys that start with prefix, on every master in cluster mode.
+func (s *RedisStorage) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	pattern := redisGlobEscaper.Replace(prefix) + "*"
+	var lock sync.Mutex
+	var keys []string
+	scan := func(ctx context.Context, client redis.UniversalClient) error {
+		iter := client.Scan(ctx, 0, pattern, 1000).Iterator()
+		for iter.Next(ctx) {
+			lock.Lock()
+			keys = append(keys, iter.Val())
+			lock.Unlock()
+		}
+		return iter.Err()
+	}
+	var err error
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+	} else {
+		err = scan(ctx, s.client)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to scan keys %s, err: %w", prefix, err)
+	}
+	return keys, nil
+}
+
+func (s *RedisStorage) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return 0, ctx.Err()
+	}
+	ttl, err := s.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("fail to get ttl of %s, err: %w", key, err)
+	}
+	// redis returns -2 when the key doesn't exist and -1 when it has no expiration
+	switch {
+	case ttl == -2:
+		return 0, fmt.Errorf("fail to get ttl of %s, err: %w", key, ErrNotFound)
+	case ttl < 0:
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *RedisStorage) SessionKeys(ctx context.Context, sessionID string) ([]string, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	keys, err := s.client.SMembers(ctx, SessionIndexKey(sessionID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fail to get keys of session %s, err: %w", sessionID, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+var _ UserStore = (*RedisStorage)(nil)
+
+// users are stored as JSON in a hash by ID, the sequence assigns the IDs
+var (
+	redisUsersKey        = keyPrefix + keySeparator + "users"
+	redisUserSequenceKey = keyPrefix + keySeparator + "users-seq"
+)
+
+func (s *RedisStorage) ListUsers(ctx context.Context, afterID int64, limit int) ([]model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return nil, ctx.Err()
+	}
+	values, err := s.client.HVals(ctx, redisUsersKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fail to list users, err: %w", err)
+	}
+	users := make([]model.User, 0, len(values))
+	for _, v := range values {
+		var u model.User
+		if err := json.Unmarshal([]byte(v), &u); err != nil {
+			return nil, fmt.Errorf("fail to unmarshal user, err: %w", err)
+		}
+		users = append(users, u)
+	}
+	return pageUsers(users, afterID, limit), nil
+}
+
+func (s *RedisStorage) GetUser(ctx context.Context, id int64) (model.User, error) {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return model.User{}, ctx.Err()
+	}
+	v, err := s.client.HGet(ctx, redisUsersKey, strconv.FormatInt(id, 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return model.User{}, fmt.Errorf("fail to get user %d, err: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return model.User{}, fmt.Errorf("fail to get user %d, err: %w", id, err)
+	}
+	var u model.User
+	if err := json.Unmarshal([]byte(v), &u); err != nil {
+		return model.User{}, fmt.Errorf("fail to unmarshal user %d, err: %w", id, err)
+	}
+	return u, nil
+}
+
+func (s *RedisStorage) SaveUser(ctx context.Context, user *model.User) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	create := user.ID == 0
+	if create {
+		id, err := s.client.Incr(ctx, redisUserSequenceKey).Result()
+		if err != nil {
+			return fmt.Errorf("fail to assign user id, err: %w", err)
+		}
+		user.ID = id
+	}
+	buf, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("fail to marshal user %d, err: %w", user.ID, err)
+	}
+	field := strconv.FormatInt(user.ID, 10)
+	if create {
+		err = s.client.HSet(ctx, redisUsersKey, field, buf).Err()
+	} else {
+		// HSETXX doesn't exist, a script keeps the check and the update atomic
+		var updated int64
+		updated, err = redisReplaceField.Run(ctx, s.client, []string{redisUsersKey}, field, buf).Int64()
+		if err == nil && updated == 0 {
+			return fmt.Errorf("fail to save user %d, err: %w", user.ID, ErrNotFound)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("fail to save user %d, err: %w", user.ID, err)
+	}
+	return nil
+}
+
+// redisReplaceField sets a hash field only when it exists, it returns 1 when the field was replaced.
+var redisReplaceField = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+func (s *RedisStorage) DeleteUser(ctx context.Context, id int64) error {
+	if contexthelper.CheckCancellation(ctx) != nil {
+		return ctx.Err()
+	}
+	deleted, err := s.client.HDel(ctx, redisUsersKey, strconv.FormatInt(id, 10)).Result()
+	if err != nil {
+		return fmt.Errorf("fail to delete user %d, err: %w", id, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("fail to delete user %d, err: %w", id, ErrNotFound)
+	}
+	return nil
+}
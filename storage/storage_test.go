@@ -0,0 +1,771 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/storage"
+	"github.com/vultisig/vultisig-relay/storage/storagetest"
+)
+
+func TestInMemoryStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			s, err := storage.NewInMemoryStorage(expiration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	})
+}
+
+// TestInMemorySessionIndexRace adds session keys while sessions are deleted and their indexes expire,
+// run it with -race.
+func TestInMemorySessionIndexRace(t *testing.T) {
+	ctx := context.Background()
+	s, err := storage.NewInMemoryStorage(config.Expiration{Value: config.Duration(time.Millisecond)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	inspector := s.(storage.Inspector)
+	const rounds = 200
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			key := storage.MessageKey("session", "a", strconv.Itoa(i))
+			if err := s.AddSessionKey(ctx, "session", key); err != nil {
+				t.Errorf("fail to add session key, err: %v", err)
+			}
+			if err := s.SetValue(ctx, key, "value"); err != nil {
+				t.Errorf("fail to set value, err: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if err := s.DeleteSession(ctx, "session"); err != nil {
+				t.Errorf("fail to delete session, err: %v", err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			storage.SweepInMemory(s)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if _, err := inspector.SessionKeys(ctx, "session"); err != nil {
+				t.Errorf("fail to get session keys, err: %v", err)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestRedisStorage(t *testing.T) {
+	var mr *miniredis.Miniredis
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			mr = miniredis.RunT(t)
+			s, err := storage.NewRedisStorage(config.RedisServer{Addr: mr.Addr()}, expiration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+		// miniredis only expires keys when time is fast-forwarded
+		Elapse: func(d time.Duration) {
+			mr.FastForward(d)
+		},
+	})
+}
+
+// TestRedisClusterStorage runs the suite with a cluster client, miniredis answers as a cluster of one node.
+func TestRedisClusterStorage(t *testing.T) {
+	var mr *miniredis.Miniredis
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			mr = miniredis.RunT(t)
+			s, err := storage.NewRedisStorage(config.RedisServer{Mode: config.RedisModeCluster, Addrs: []string{mr.Addr()}}, expiration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+		Elapse: func(d time.Duration) {
+			mr.FastForward(d)
+		},
+	})
+}
+
+func TestRedisModes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	tests := []struct {
+		name    string
+		cfg     config.RedisServer
+		wantErr bool
+	}{
+		{name: "standalone", cfg: config.RedisServer{Mode: config.RedisModeStandalone, Addr: mr.Addr()}},
+		{name: "cluster seed nodes", cfg: config.RedisServer{Mode: config.RedisModeCluster, Addrs: []string{mr.Addr()}}},
+		{name: "cluster addr", cfg: config.RedisServer{Mode: config.RedisModeCluster, Addr: mr.Addr()}},
+		{name: "cluster db", cfg: config.RedisServer{Mode: config.RedisModeCluster, Addr: mr.Addr(), DB: 1}, wantErr: true},
+		{name: "sentinel without master", cfg: config.RedisServer{Mode: config.RedisModeSentinel, Addr: mr.Addr()}, wantErr: true},
+		{name: "unknown mode", cfg: config.RedisServer{Mode: "ring", Addr: mr.Addr()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := storage.NewRedisStorage(tt.cfg, config.Expiration{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if s != nil {
+				_ = s.Close()
+			}
+		})
+	}
+}
+
+func TestBoltStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			s, err := storage.NewBoltStorage(config.Storage{
+				Path:       filepath.Join(t.TempDir(), "relay.db"),
+				Expiration: expiration,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	})
+}
+
+func TestBoltStorageClose(t *testing.T) {
+	s, err := storage.NewBoltStorage(config.Storage{Path: filepath.Join(t.TempDir(), "relay.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("fail to close storage, err: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("fail to close storage twice, err: %v", err)
+	}
+	u := model.User{APIKey: "key"}
+	if err := s.SaveUser(context.Background(), &u); err == nil {
+		t.Fatal("expected saving a user to a closed storage to fail")
+	}
+	if u.ID != 0 {
+		t.Fatalf("expected no ID for a user that wasn't saved, got %d", u.ID)
+	}
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			s, err := storage.NewSQLStorage("sqlite://"+filepath.Join(t.TempDir(), "relay.db"), expiration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	})
+}
+
+// postgresEnv is the connection string of a Postgres database the SQL storage is tested against, when set.
+const postgresEnv = "RELAY_TEST_POSTGRES"
+
+// newPostgresSchema creates an empty schema in the test database and returns a connection string that uses it.
+func newPostgresSchema(t *testing.T) string {
+	t.Helper()
+	connectionString := os.Getenv(postgresEnv)
+	if connectionString == "" {
+		t.Skipf("%s is not set", postgresEnv)
+	}
+	db, err := sql.Open("pgx", connectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	schema := fmt.Sprintf("relay_test_%d", time.Now().UnixNano())
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("fail to create schema, err: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("fail to drop schema, err: %v", err)
+		}
+	})
+	separator := "?"
+	if strings.Contains(connectionString, "?") {
+		separator = "&"
+	}
+	return connectionString + separator + "search_path=" + schema
+}
+
+func TestPostgresStorage(t *testing.T) {
+	if os.Getenv(postgresEnv) == "" {
+		t.Skipf("%s is not set", postgresEnv)
+	}
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			s, err := storage.NewSQLStorage(newPostgresSchema(t), expiration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	})
+}
+
+// TestPostgresConcurrentMigrations starts relays together on an empty database, each migration is applied once.
+func TestPostgresConcurrentMigrations(t *testing.T) {
+	connectionString := newPostgresSchema(t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := storage.NewSQLStorage(connectionString, config.Expiration{})
+			if err == nil {
+				err = s.Close()
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("fail to start relay, err: %v", err)
+		}
+	}
+	db, err := sql.Open("pgx", connectionString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var versions, distinct int
+	if err := db.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT version) FROM relay_schema_migrations`).Scan(&versions, &distinct); err != nil {
+		t.Fatal(err)
+	}
+	if versions == 0 || versions != distinct {
+		t.Fatalf("expected every migration once, got %d rows for %d versions", versions, distinct)
+	}
+}
+
+func TestSQLiteReopen(t *testing.T) {
+	ctx := context.Background()
+	connectionString := "sqlite://" + filepath.Join(t.TempDir(), "relay.db")
+	s, err := storage.NewSQLStorage(connectionString, config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetValue(ctx, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the migrations are already applied, they are not applied again
+	s, err = storage.NewSQLStorage(connectionString, config.Expiration{})
+	if err != nil {
+		t.Fatalf("fail to reopen database, err: %v", err)
+	}
+	defer s.Close()
+	if value, err := s.GetValue(ctx, "key"); err != nil || value != "value" {
+		t.Fatalf("expected the value to be kept, got %q, err: %v", value, err)
+	}
+}
+
+func TestLegacyKeyStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			s, err := storage.NewInMemoryStorage(expiration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return storage.NewLegacyKeyStorage(s)
+		},
+	})
+
+	t.Run("DeleteLegacySession", func(t *testing.T) {
+		ctx := context.Background()
+		inner, err := storage.NewInMemoryStorage(config.Expiration{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer inner.Close()
+		s := storage.NewLegacyKeyStorage(inner)
+		// keys written by relays before the key builder
+		write := func(sessionID string) []string {
+			t.Helper()
+			if err := inner.SetSession(ctx, sessionID, []string{"a", "b"}); err != nil {
+				t.Fatalf("fail to set legacy session, err: %v", err)
+			}
+			messageKeys := []string{sessionID + "-a", sessionID + "-b-msg1"}
+			for _, key := range messageKeys {
+				if err := inner.SetMessage(ctx, key, model.Message{SessionID: sessionID, From: "b", Hash: "hash"}); err != nil {
+					t.Fatalf("fail to set legacy message, err: %v", err)
+				}
+			}
+			valueKeys := []string{"setup-" + sessionID, "setup-" + sessionID + "-msg1", "keysign-" + sessionID + "-msg1-complete"}
+			for _, key := range valueKeys {
+				if err := inner.SetValue(ctx, key, "value"); err != nil {
+					t.Fatalf("fail to set legacy value, err: %v", err)
+				}
+			}
+			return append([]string{sessionID}, append(messageKeys, valueKeys...)...)
+		}
+		deleted := write("deleted")
+		kept := write("kept")
+		// setup-deleted-m is the setup message of deleted-m, not a message of deleted
+		shared := write("deleted-m")
+
+		if err := s.DeleteSession(ctx, "deleted"); err != nil {
+			t.Fatalf("fail to delete session, err: %v", err)
+		}
+		inspector := inner.(storage.Inspector)
+		check := func(keys []string, exist bool) {
+			t.Helper()
+			for _, key := range keys {
+				found, err := inspector.Keys(ctx, key)
+				if err != nil {
+					t.Fatalf("fail to list keys, err: %v", err)
+				}
+				got := false
+				for _, k := range found {
+					got = got || k == key
+				}
+				if got != exist {
+					t.Errorf("legacy key %s: expected exist %v, got %v", key, exist, got)
+				}
+			}
+		}
+		check(deleted, false)
+		check(kept, true)
+		check(shared, true)
+	})
+}
+
+func TestCompressedStorage(t *testing.T) {
+	for _, algorithm := range []string{config.CompressionGzip, config.CompressionZstd} {
+		t.Run(algorithm, func(t *testing.T) {
+			storagetest.Run(t, storagetest.Harness{
+				New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+					s, err := storage.NewInMemoryStorage(expiration)
+					if err != nil {
+						t.Fatal(err)
+					}
+					compressed, err := storage.NewCompressedStorage(s, config.Compression{Algorithm: algorithm})
+					if err != nil {
+						t.Fatal(err)
+					}
+					return compressed
+				},
+			})
+		})
+	}
+}
+
+// TestCompressedStorageFormats reads values written with other algorithms, or before compression was enabled.
+func TestCompressedStorageFormats(t *testing.T) {
+	ctx := context.Background()
+	backend, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	open := func(algorithm string) storage.Storage {
+		s, err := storage.NewCompressedStorage(backend, config.Compression{Algorithm: algorithm, MinSize: 1024})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	large := strings.Repeat("keygen payload ", 1000)
+	values := map[string]string{
+		"old":       large,
+		"gzip":      large,
+		"zstd":      large,
+		"small":     "signature",
+		"marker":    "\x00vrzz not compressed",
+		"shortmark": "\x00vrz",
+	}
+	if err := backend.SetValue(ctx, "old", values["old"]); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range values {
+		algorithm := config.CompressionZstd
+		switch key {
+		case "old":
+			continue
+		case "gzip":
+			algorithm = config.CompressionGzip
+		}
+		if err := open(algorithm).SetValue(ctx, key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for key, want := range values {
+		stored, err := backend.GetValue(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compressed := key == "gzip" || key == "zstd"; compressed != (len(stored) < len(want)) {
+			t.Errorf("%s: stored %d bytes for a value of %d bytes", key, len(stored), len(want))
+		}
+		// the values stay readable once compression is disabled
+		got, err := open(config.CompressionNone).GetValue(ctx, key)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %d bytes, got %d bytes, err: %v", key, len(want), len(got), err)
+		}
+	}
+}
+
+func testEncryptionKey(id string, b byte) string {
+	return id + ":" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	storagetest.Run(t, storagetest.Harness{
+		New: func(t *testing.T, expiration config.Expiration) storage.Storage {
+			s, err := storage.NewInMemoryStorage(expiration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			encrypted, err := storage.NewEncryptedStorage(s, config.Encryption{Keys: []string{testEncryptionKey("k1", 1)}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return encrypted
+		},
+	})
+}
+
+// TestEncryptedStorageRotation reads values written with a rotated key, or before encryption was enabled.
+func TestEncryptedStorageRotation(t *testing.T) {
+	ctx := context.Background()
+	backend, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	open := func(keys ...string) storage.Storage {
+		s, err := storage.NewEncryptedStorage(backend, config.Encryption{Keys: keys})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	keyA, keyB := testEncryptionKey("a", 1), testEncryptionKey("b", 2)
+	const value = "keygen setup message"
+	if err := open(keyA).SetValue(ctx, "old", value); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.SetValue(ctx, "plain", value); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := backend.GetValue(ctx, "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored, value) {
+		t.Fatalf("expected an encrypted value, got %q", stored)
+	}
+
+	// the new key encrypts, the old one still decrypts
+	rotated := open(keyB, keyA)
+	if err := rotated.SetValue(ctx, "new", value); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"old", "new", "plain"} {
+		if got, err := rotated.GetValue(ctx, key); err != nil || got != value {
+			t.Errorf("%s: expected %q, got %q, err: %v", key, value, got, err)
+		}
+	}
+	if _, err := open(keyB).GetValue(ctx, "old"); err == nil {
+		t.Error("expected an error reading a value of a dropped key")
+	}
+	if _, err := open(testEncryptionKey("a", 3)).GetValue(ctx, "old"); err == nil {
+		t.Error("expected an error reading a value with a wrong key")
+	}
+
+	// values can't be tampered with or moved to another key
+	if err := backend.SetValue(ctx, "moved", stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.GetValue(ctx, "moved"); err == nil {
+		t.Error("expected an error reading a value moved to another key")
+	}
+	tampered := []byte(stored)
+	tampered[len(tampered)-1] ^= 1
+	if err := backend.SetValue(ctx, "old", string(tampered)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.GetValue(ctx, "old"); err == nil {
+		t.Error("expected an error reading a tampered value")
+	}
+}
+
+func TestEncryptionKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# current key first\n" + testEncryptionKey("b", 2) + "\n\n" + testEncryptionKey("a", 1) + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	ctx := context.Background()
+	old, err := storage.NewEncryptedStorage(backend, config.Encryption{Keys: []string{testEncryptionKey("a", 1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := old.SetValue(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := storage.NewEncryptedStorage(backend, config.Encryption{KeyFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.GetValue(ctx, "k"); err != nil || got != "v" {
+		t.Fatalf("expected v, got %q, err: %v", got, err)
+	}
+	for _, cfg := range []config.Encryption{
+		{},
+		{Keys: []string{"a:short"}},
+		{Keys: []string{testEncryptionKey("a", 1), testEncryptionKey("a", 2)}},
+		{KeyFile: filepath.Join(t.TempDir(), "missing")},
+	} {
+		if _, err := storage.NewEncryptedStorage(backend, cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestPayloadChunks stores a payload in chunks and reads ranges of it across the chunks.
+func TestPayloadChunks(t *testing.T) {
+	ctx := context.Background()
+	s, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	payload := strings.Repeat("0123456789abcdef", storage.PayloadChunkSize/16*5/2)
+	id := storage.PayloadID{Algorithm: storage.HashSHA256, Digest: sha256Hex(payload)}
+	w := storage.NewPayloadWriter(ctx, s, storage.PayloadManifest{ID: "p1"})
+	if _, err := io.Copy(w, strings.NewReader(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if chunks := len(w.Manifest().Chunks); chunks != 3 {
+		t.Fatalf("expected 3 chunks, got %d", chunks)
+	}
+	if err := storage.StorePayload(ctx, s, id, id.Digest, w.Manifest(), nil); err != nil {
+		t.Fatal(err)
+	}
+	p, err := storage.GetPayload(ctx, s, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range [][2]int64{{0, p.Size()}, {10, 20}, {storage.PayloadChunkSize - 5, storage.PayloadChunkSize + 5}, {p.Size() - 1, p.Size()}, {7, 7}} {
+		reader, err := p.NewReader(ctx, r[0], r[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(reader)
+		if err != nil || string(got) != payload[r[0]:r[1]] {
+			t.Errorf("range %v: expected %d bytes, got %d bytes, err: %v", r, r[1]-r[0], len(got), err)
+		}
+	}
+
+	// a payload stored whole, before payloads were chunked, is still read
+	if err := s.SetValue(ctx, storage.PayloadKey(sha256Hex("whole")), "whole"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := storage.GetPayload(ctx, s, storage.PayloadID{Algorithm: storage.HashSHA256, Digest: sha256Hex("whole")}); err != nil || p.Size() != 5 {
+		t.Fatalf("expected the whole payload, err: %v", err)
+	}
+
+	if err := s.SetValue(ctx, storage.PayloadChunkKey("p1", 1), "tampered"); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := p.NewReader(ctx, 0, p.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(reader); !errors.Is(err, storage.ErrCorruptPayload) {
+		t.Fatalf("expected a corrupt payload, err: %v", err)
+	}
+	if _, err := p.NewReader(ctx, storage.PayloadChunkSize, p.Size()); !errors.Is(err, storage.ErrCorruptPayload) {
+		t.Fatalf("expected a corrupt first chunk to fail before reading, err: %v", err)
+	}
+	if err := storage.CommitPayload(ctx, s, id, w.Manifest(), nil); !errors.Is(err, storage.ErrCorruptPayload) {
+		t.Fatalf("expected a corrupt payload not to be committed, err: %v", err)
+	}
+}
+
+func TestParsePayloadID(t *testing.T) {
+	digest := sha256Hex("payload")
+	tests := []struct {
+		input   string
+		want    storage.PayloadID
+		wantErr bool
+	}{
+		{input: digest, want: storage.PayloadID{Algorithm: storage.HashSHA256, Digest: digest}},
+		{input: "sha2-256:" + digest, want: storage.PayloadID{Algorithm: storage.HashSHA256, Digest: digest}},
+		{input: "blake3:" + digest, want: storage.PayloadID{Algorithm: storage.HashBLAKE3, Digest: digest}},
+		{input: "sha2-512-256:" + digest, want: storage.PayloadID{Algorithm: storage.HashSHA512256, Digest: digest}},
+		{input: "md5:" + digest, wantErr: true},
+		{input: strings.ToUpper(digest), wantErr: true},
+		{input: digest[:62], wantErr: true},
+		{input: "blake3:", wantErr: true},
+	}
+	for _, tt := range tests {
+		id, err := storage.ParsePayloadID(tt.input)
+		if (err != nil) != tt.wantErr || id != tt.want {
+			t.Errorf("%s: expected %+v, got %+v, err: %v", tt.input, tt.want, id, err)
+		}
+	}
+	if id, _ := storage.ParsePayloadID("sha2-256:" + digest); id.String() != digest {
+		t.Errorf("expected a SHA-256 to be written as a bare digest, got %s", id.String())
+	}
+}
+
+// storePayload stores payload with the ID of the algorithm for the sessions, as an upload does.
+func storePayload(t *testing.T, s storage.Storage, algorithm, payload string, sessions ...string) storage.PayloadID {
+	t.Helper()
+	ctx := context.Background()
+	hash := storage.PayloadID{Algorithm: algorithm}.NewHash()
+	_, _ = io.WriteString(hash, payload)
+	id := storage.PayloadID{Algorithm: algorithm, Digest: hex.EncodeToString(hash.Sum(nil))}
+	manifestID, err := storage.NewPayloadID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := storage.NewPayloadWriter(ctx, s, storage.PayloadManifest{ID: manifestID})
+	if _, err := io.WriteString(w, payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.CommitPayload(ctx, s, id, w.Manifest(), sessions); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func countKeys(t *testing.T, s storage.Storage, kind storage.KeyKind) int {
+	t.Helper()
+	keys, err := s.(storage.Inspector).Keys(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, key := range keys {
+		if k, _, err := storage.ParseKey(key); err == nil && k == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// TestPayloadReferences stores the same content with several algorithms and sessions, and frees it with the
+// last session.
+func TestPayloadReferences(t *testing.T) {
+	ctx := context.Background()
+	s, err := storage.NewInMemoryStorage(config.Expiration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	payload := "keysign payload"
+	sha := storePayload(t, s, storage.HashSHA256, payload, "s1")
+	blake := storePayload(t, s, storage.HashBLAKE3, payload, "s2")
+	for _, id := range []storage.PayloadID{sha, blake} {
+		p, err := storage.GetPayload(ctx, s, id)
+		if err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		r, err := p.NewReader(ctx, 0, p.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := io.ReadAll(r); err != nil || string(got) != payload {
+			t.Fatalf("%s: expected the payload, got %q, err: %v", id, got, err)
+		}
+	}
+	// the content is stored once, the chunks of the second upload were deleted
+	if chunks := countKeys(t, s, storage.KindPayloadChunk); chunks != 1 {
+		t.Fatalf("expected the content to be stored once, got %d chunks", chunks)
+	}
+
+	if err := storage.ReleaseSessionPayloads(ctx, s, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.GetPayload(ctx, s, blake); err != nil {
+		t.Fatalf("expected the payload to be kept for s2, err: %v", err)
+	}
+	if err := storage.ReleaseSessionPayloads(ctx, s, "s2"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []storage.PayloadID{sha, blake} {
+		if _, err := storage.GetPayload(ctx, s, id); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("%s: expected the payload to be freed, err: %v", id, err)
+		}
+	}
+	for _, kind := range []storage.KeyKind{storage.KindPayload, storage.KindPayloadChunk, storage.KindPayloadAlias, storage.KindPayloadReaders, storage.KindPayloadAliases, storage.KindSessionPayloads} {
+		if n := countKeys(t, s, kind); n != 0 {
+			t.Fatalf("expected no %s keys left, got %d", kind, n)
+		}
+	}
+
+	// a payload stored without a session is kept until it expires
+	unbound := storePayload(t, s, storage.HashSHA512256, payload)
+	storePayload(t, s, storage.HashSHA256, payload, "s3")
+	if err := storage.ReleaseSessionPayloads(ctx, s, "s3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.GetPayload(ctx, s, unbound); err != nil {
+		t.Fatalf("expected the payload stored without a session to be kept, err: %v", err)
+	}
+}
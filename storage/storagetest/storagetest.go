@@ -0,0 +1,722 @@
+// Package storagetest is a conformance suite for storage.Storage implementations.
+// Every backend runs the same suite, so they all behave like RedisStorage.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vultisig/vultisig-relay/config"
+	"github.com/vultisig/vultisig-relay/model"
+	"github.com/vultisig/vultisig-relay/storage"
+)
+
+// Harness creates the storage under test.
+type Harness struct {
+	// New returns an empty storage that uses the given expiration, it is closed by the suite
+	New func(t *testing.T, expiration config.Expiration) storage.Storage
+	// Elapse lets the given duration pass for the storage, it sleeps when nil
+	Elapse func(d time.Duration)
+}
+
+func (h Harness) open(t *testing.T, expiration config.Expiration) storage.Storage {
+	t.Helper()
+	s := h.New(t, expiration)
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Errorf("fail to close storage, err: %v", err)
+		}
+	})
+	return s
+}
+
+func (h Harness) elapse(d time.Duration) {
+	if h.Elapse != nil {
+		h.Elapse(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// Run executes the conformance suite against the storage created by the harness.
+func Run(t *testing.T, h Harness) {
+	t.Run("Session", func(t *testing.T) { testSession(t, h) })
+	t.Run("Messages", func(t *testing.T) { testMessages(t, h) })
+	t.Run("Values", func(t *testing.T) { testValues(t, h) })
+	t.Run("Sets", func(t *testing.T) { testSets(t, h) })
+	t.Run("DeleteSession", func(t *testing.T) { testDeleteSession(t, h) })
+	t.Run("Expiration", func(t *testing.T) { testExpiration(t, h) })
+	t.Run("Cancellation", func(t *testing.T) { testCancellation(t, h) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, h) })
+	t.Run("Inspector", func(t *testing.T) { testInspector(t, h) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, h) })
+}
+
+func newMessage(sessionID, from string, seq uint64) model.Message {
+	return model.Message{
+		SessionID:  sessionID,
+		From:       from,
+		To:         []string{"peer"},
+		Body:       []byte(fmt.Sprintf("body-%d", seq)),
+		Hash:       fmt.Sprintf("hash-%d", seq),
+		SequenceNo: seq,
+	}
+}
+
+func hashes(messages []model.Message) []string {
+	result := make([]string, 0, len(messages))
+	for _, m := range messages {
+		result = append(result, m.Hash)
+	}
+	return result
+}
+
+func testSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+	key := storage.SessionKey("session")
+
+	participants, err := s.GetSession(ctx, key)
+	if err != nil {
+		t.Fatalf("missing session: unexpected error %v", err)
+	}
+	if participants == nil || len(participants) != 0 {
+		t.Fatalf("missing session: expected empty participants, got %#v", participants)
+	}
+
+	if err := s.SetSession(ctx, key, []string{"a", "b"}); err != nil {
+		t.Fatalf("fail to set session, err: %v", err)
+	}
+	if err := s.SetSession(ctx, key, []string{"b", "c", "a"}); err != nil {
+		t.Fatalf("fail to set session, err: %v", err)
+	}
+	// joining again is not an error and adds nothing
+	if err := s.SetSession(ctx, key, []string{"c"}); err != nil {
+		t.Fatalf("fail to rejoin session, err: %v", err)
+	}
+	if err := s.SetSession(ctx, key, nil); err != nil {
+		t.Fatalf("fail to set session without participants, err: %v", err)
+	}
+	participants, err = s.GetSession(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get session, err: %v", err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(participants, want) {
+		t.Fatalf("expected participants %v in join order, got %v", want, participants)
+	}
+
+	// the returned slice is a copy
+	participants[0] = "changed"
+	participants, err = s.GetSession(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get session, err: %v", err)
+	}
+	if participants[0] != "a" {
+		t.Fatalf("modifying the result changed the stored session: %v", participants)
+	}
+}
+
+func testMessages(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+	key := storage.MessageKey("session", "a", "")
+
+	messages, err := s.GetMessages(ctx, key)
+	if err != nil {
+		t.Fatalf("missing messages: unexpected error %v", err)
+	}
+	if messages == nil || len(messages) != 0 {
+		t.Fatalf("missing messages: expected empty messages, got %#v", messages)
+	}
+
+	for _, seq := range []uint64{3, 1, 2} {
+		if err := s.SetMessage(ctx, key, newMessage("session", "b", seq)); err != nil {
+			t.Fatalf("fail to set message, err: %v", err)
+		}
+	}
+	// a message with a known hash is dropped, even if the body differs
+	dup := newMessage("session", "b", 1)
+	dup.Body = []byte("other")
+	if err := s.SetMessage(ctx, key, dup); err != nil {
+		t.Fatalf("fail to set duplicate message, err: %v", err)
+	}
+	messages, err = s.GetMessages(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if want := []string{"hash-3", "hash-1", "hash-2"}; !reflect.DeepEqual(hashes(messages), want) {
+		t.Fatalf("expected messages %v in arrival order, got %v", want, hashes(messages))
+	}
+	if want := newMessage("session", "b", 1); !reflect.DeepEqual(messages[1], want) {
+		t.Fatalf("expected message %#v, got %#v", want, messages[1])
+	}
+
+	if err := s.DeleteMessage(ctx, key, "hash-1"); err != nil {
+		t.Fatalf("fail to delete message, err: %v", err)
+	}
+	if err := s.DeleteMessage(ctx, key, "unknown"); err != nil {
+		t.Fatalf("fail to delete unknown message, err: %v", err)
+	}
+	messages, err = s.GetMessages(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if want := []string{"hash-3", "hash-2"}; !reflect.DeepEqual(hashes(messages), want) {
+		t.Fatalf("expected messages %v after delete, got %v", want, hashes(messages))
+	}
+
+	// a deleted message can be delivered again
+	if err := s.SetMessage(ctx, key, newMessage("session", "b", 1)); err != nil {
+		t.Fatalf("fail to set message, err: %v", err)
+	}
+	messages, err = s.GetMessages(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if want := []string{"hash-3", "hash-2", "hash-1"}; !reflect.DeepEqual(hashes(messages), want) {
+		t.Fatalf("expected messages %v after redelivery, got %v", want, hashes(messages))
+	}
+
+	// bodies are binary, they are stored as is
+	binary := newMessage("session", "b", 4)
+	binary.Body = []byte{0x00, 0xff, 0xfe, '"', 0x80}
+	if err := s.SetMessage(ctx, key, binary); err != nil {
+		t.Fatalf("fail to set binary message, err: %v", err)
+	}
+	messages, err = s.GetMessages(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if len(messages) != 4 || !reflect.DeepEqual(messages[3], binary) {
+		t.Fatalf("expected binary message %#v, got %#v", binary, messages)
+	}
+	if err := s.DeleteMessage(ctx, key, "hash-4"); err != nil {
+		t.Fatalf("fail to delete binary message, err: %v", err)
+	}
+	messages, err = s.GetMessages(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if want := []string{"hash-3", "hash-2", "hash-1"}; !reflect.DeepEqual(hashes(messages), want) {
+		t.Fatalf("expected messages %v after deleting the binary message, got %v", want, hashes(messages))
+	}
+
+	if err := s.DeleteMessages(ctx, key); err != nil {
+		t.Fatalf("fail to delete messages, err: %v", err)
+	}
+	if err := s.DeleteMessages(ctx, key); err != nil {
+		t.Fatalf("fail to delete missing messages, err: %v", err)
+	}
+	messages, err = s.GetMessages(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("expected no messages after delete, got %v", hashes(messages))
+	}
+}
+
+func testValues(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+	key := storage.PayloadKey("hash")
+
+	if _, err := s.GetValue(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("missing value: expected ErrNotFound, got %v", err)
+	}
+	// values are binary safe
+	value := string([]byte{0, 1, 2, 0xff, 0xfe, '\n', 0})
+	if err := s.SetValue(ctx, key, value); err != nil {
+		t.Fatalf("fail to set value, err: %v", err)
+	}
+	got, err := s.GetValue(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get value, err: %v", err)
+	}
+	if got != value {
+		t.Fatalf("expected value %q, got %q", value, got)
+	}
+	if err := s.SetValue(ctx, key, "updated"); err != nil {
+		t.Fatalf("fail to overwrite value, err: %v", err)
+	}
+	if got, err = s.GetValue(ctx, key); err != nil || got != "updated" {
+		t.Fatalf("expected overwritten value, got %q, err: %v", got, err)
+	}
+	// keygen payloads are hundreds of KB
+	large := strings.Repeat(`{"round":1,"payload":"0123456789abcdef"}`, 10000)
+	if err := s.SetValue(ctx, key, large); err != nil {
+		t.Fatalf("fail to set large value, err: %v", err)
+	}
+	if got, err = s.GetValue(ctx, key); err != nil || got != large {
+		t.Fatalf("expected large value of %d bytes, got %d bytes, err: %v", len(large), len(got), err)
+	}
+	if err := s.DeleteValue(ctx, key); err != nil {
+		t.Fatalf("fail to delete value, err: %v", err)
+	}
+	if _, err := s.GetValue(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("deleted value: expected ErrNotFound, got %v", err)
+	}
+	if err := s.DeleteValue(ctx, key); err != nil {
+		t.Fatalf("fail to delete missing value, err: %v", err)
+	}
+}
+
+func testSets(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+	key := storage.PayloadReadersKey("hash")
+
+	members, err := s.GetSetMembers(ctx, key)
+	if err != nil || len(members) != 0 {
+		t.Fatalf("missing set: expected no members, got %v, err: %v", members, err)
+	}
+	if err := s.AddSetMembers(ctx, key, "a", "b"); err != nil {
+		t.Fatalf("fail to add members, err: %v", err)
+	}
+	// a member is stored once
+	if err := s.AddSetMembers(ctx, key, "b", "c"); err != nil {
+		t.Fatalf("fail to add members, err: %v", err)
+	}
+	members, err = s.GetSetMembers(ctx, key)
+	if err != nil {
+		t.Fatalf("fail to get members, err: %v", err)
+	}
+	sort.Strings(members)
+	if !reflect.DeepEqual(members, []string{"a", "b", "c"}) {
+		t.Fatalf("expected members [a b c], got %v", members)
+	}
+	for i, member := range []string{"a", "missing", "b", "c"} {
+		left, err := s.RemoveSetMember(ctx, key, member)
+		if err != nil {
+			t.Fatalf("fail to remove %s, err: %v", member, err)
+		}
+		if want := []int{2, 2, 1, 0}[i]; left != want {
+			t.Fatalf("remove %s: expected %d members left, got %d", member, want, left)
+		}
+	}
+	if left, err := s.RemoveSetMember(ctx, key, "a"); err != nil || left != 0 {
+		t.Fatalf("missing set: expected no members left, got %d, err: %v", left, err)
+	}
+
+	// removals are atomic, exactly one of them removes the last member
+	const workers = 8
+	for w := 0; w < workers; w++ {
+		if err := s.AddSetMembers(ctx, key, fmt.Sprintf("s%d", w)); err != nil {
+			t.Fatalf("fail to add member, err: %v", err)
+		}
+	}
+	var wg sync.WaitGroup
+	lefts := make(chan int, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			left, err := s.RemoveSetMember(ctx, key, fmt.Sprintf("s%d", w))
+			if err != nil {
+				t.Errorf("fail to remove member, err: %v", err)
+				return
+			}
+			lefts <- left
+		}(w)
+	}
+	wg.Wait()
+	close(lefts)
+	seen := make(map[int]bool)
+	for left := range lefts {
+		if seen[left] {
+			t.Fatalf("two removals left %d members", left)
+		}
+		seen[left] = true
+	}
+
+	if err := s.AddSetMembers(ctx, key, "a"); err != nil {
+		t.Fatalf("fail to add member, err: %v", err)
+	}
+	if err := s.DeleteValue(ctx, key); err != nil {
+		t.Fatalf("fail to delete set, err: %v", err)
+	}
+	if members, err := s.GetSetMembers(ctx, key); err != nil || len(members) != 0 {
+		t.Fatalf("deleted set: expected no members, got %v, err: %v", members, err)
+	}
+}
+
+func testDeleteSession(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+
+	write := func(sessionID string) []string {
+		t.Helper()
+		sessionKey := storage.SessionKey(sessionID)
+		messageKey := storage.MessageKey(sessionID, "a", "")
+		setupKey := storage.SetupKey(sessionID, "")
+		for _, key := range []string{messageKey, setupKey} {
+			if err := s.AddSessionKey(ctx, sessionID, key); err != nil {
+				t.Fatalf("fail to add session key, err: %v", err)
+			}
+		}
+		if err := s.SetSession(ctx, sessionKey, []string{"a", "b"}); err != nil {
+			t.Fatalf("fail to set session, err: %v", err)
+		}
+		if err := s.SetMessage(ctx, messageKey, newMessage(sessionID, "b", 1)); err != nil {
+			t.Fatalf("fail to set message, err: %v", err)
+		}
+		if err := s.SetValue(ctx, setupKey, "setup"); err != nil {
+			t.Fatalf("fail to set value, err: %v", err)
+		}
+		return []string{sessionKey, messageKey, setupKey}
+	}
+	deleted := write("deleted")
+	kept := write("kept")
+
+	if err := s.DeleteSession(ctx, "deleted"); err != nil {
+		t.Fatalf("fail to delete session, err: %v", err)
+	}
+	if err := s.DeleteSession(ctx, "missing"); err != nil {
+		t.Fatalf("fail to delete missing session, err: %v", err)
+	}
+
+	check := func(keys []string, exist bool) {
+		t.Helper()
+		participants, err := s.GetSession(ctx, keys[0])
+		if err != nil {
+			t.Fatalf("fail to get session, err: %v", err)
+		}
+		if (len(participants) > 0) != exist {
+			t.Fatalf("session %s: expected exist %v, got participants %v", keys[0], exist, participants)
+		}
+		messages, err := s.GetMessages(ctx, keys[1])
+		if err != nil {
+			t.Fatalf("fail to get messages, err: %v", err)
+		}
+		if (len(messages) > 0) != exist {
+			t.Fatalf("messages %s: expected exist %v, got %v", keys[1], exist, hashes(messages))
+		}
+		_, err = s.GetValue(ctx, keys[2])
+		if exist && err != nil {
+			t.Fatalf("value %s: unexpected error %v", keys[2], err)
+		}
+		if !exist && !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("value %s: expected ErrNotFound, got %v", keys[2], err)
+		}
+	}
+	check(deleted, false)
+	check(kept, true)
+}
+
+// testExpiration uses whole seconds, the smallest expiration redis supports.
+func testExpiration(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{
+		Session: config.Duration(time.Second),
+		Value:   config.Duration(time.Second * 3),
+	})
+	sessionKey := storage.SessionKey("session")
+	messageKey := storage.MessageKey("session", "a", "")
+	setupKey := storage.SetupKey("session", "")
+	valueKey := storage.PayloadKey("hash")
+
+	if err := s.SetSession(ctx, sessionKey, []string{"a"}); err != nil {
+		t.Fatalf("fail to set session, err: %v", err)
+	}
+	if err := s.SetMessage(ctx, messageKey, newMessage("session", "b", 1)); err != nil {
+		t.Fatalf("fail to set message, err: %v", err)
+	}
+	for _, key := range []string{setupKey, valueKey} {
+		if err := s.SetValue(ctx, key, "value"); err != nil {
+			t.Fatalf("fail to set value, err: %v", err)
+		}
+	}
+	// the setup message lives as a value but is tracked, so it expires with the session
+	for _, key := range []string{messageKey, setupKey} {
+		if err := s.AddSessionKey(ctx, "session", key); err != nil {
+			t.Fatalf("fail to add session key, err: %v", err)
+		}
+	}
+
+	h.elapse(time.Millisecond * 1500)
+	participants, err := s.GetSession(ctx, sessionKey)
+	if err != nil {
+		t.Fatalf("fail to get session, err: %v", err)
+	}
+	if len(participants) != 0 {
+		t.Fatalf("expected session to expire, got participants %v", participants)
+	}
+	messages, err := s.GetMessages(ctx, messageKey)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Fatalf("expected messages to expire, got %v", hashes(messages))
+	}
+	if _, err := s.GetValue(ctx, setupKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected the tracked value to expire with the session, got %v", err)
+	}
+	if _, err := s.GetValue(ctx, valueKey); err != nil {
+		t.Fatalf("expected the untracked value to outlive the session, err: %v", err)
+	}
+
+	// an expired session starts over when it is joined again
+	if err := s.SetSession(ctx, sessionKey, []string{"b"}); err != nil {
+		t.Fatalf("fail to set session, err: %v", err)
+	}
+	if participants, err = s.GetSession(ctx, sessionKey); err != nil || !reflect.DeepEqual(participants, []string{"b"}) {
+		t.Fatalf("expected a new session with participants [b], got %v, err: %v", participants, err)
+	}
+
+	h.elapse(time.Millisecond * 2000)
+	if _, err := s.GetValue(ctx, valueKey); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected value to expire, got %v", err)
+	}
+}
+
+func testCancellation(t *testing.T, h Harness) {
+	s := h.open(t, config.Expiration{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	key := storage.SessionKey("session")
+	messageKey := storage.MessageKey("session", "a", "")
+	calls := map[string]func() error{
+		"SetSession": func() error { return s.SetSession(ctx, key, []string{"a"}) },
+		"GetSession": func() error { _, err := s.GetSession(ctx, key); return err },
+		"DeleteSession": func() error {
+			return s.DeleteSession(ctx, "session")
+		},
+		"AddSessionKey": func() error { return s.AddSessionKey(ctx, "session", messageKey) },
+		"GetMessages":   func() error { _, err := s.GetMessages(ctx, messageKey); return err },
+		"SetMessage": func() error {
+			return s.SetMessage(ctx, messageKey, newMessage("session", "b", 1))
+		},
+		"DeleteMessages": func() error { return s.DeleteMessages(ctx, messageKey) },
+		"DeleteMessage":  func() error { return s.DeleteMessage(ctx, messageKey, "hash-1") },
+		"SetValue":       func() error { return s.SetValue(ctx, storage.PayloadKey("hash"), "value") },
+		"GetValue":       func() error { _, err := s.GetValue(ctx, storage.PayloadKey("hash")); return err },
+		"AddSetMembers":  func() error { return s.AddSetMembers(ctx, storage.PayloadReadersKey("hash"), "session") },
+		"RemoveSetMember": func() error {
+			_, err := s.RemoveSetMember(ctx, storage.PayloadReadersKey("hash"), "session")
+			return err
+		},
+		"GetSetMembers": func() error { _, err := s.GetSetMembers(ctx, storage.PayloadReadersKey("hash")); return err },
+		"Ping":          func() error { return s.Ping(ctx) },
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", name, err)
+		}
+	}
+	// nothing was written by the cancelled calls
+	participants, err := s.GetSession(context.Background(), key)
+	if err != nil {
+		t.Fatalf("fail to get session, err: %v", err)
+	}
+	if len(participants) != 0 {
+		t.Fatalf("expected no participants, got %v", participants)
+	}
+}
+
+func testConcurrency(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+	sessionKey := storage.SessionKey("session")
+	messageKey := storage.MessageKey("session", "a", "")
+	const workers = 8
+	const perWorker = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker*2)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			participant := fmt.Sprintf("p%d", w)
+			for i := 0; i < perWorker; i++ {
+				if err := s.SetSession(ctx, sessionKey, []string{participant}); err != nil {
+					errs <- err
+				}
+				if err := s.SetMessage(ctx, messageKey, newMessage("session", participant, uint64(w*perWorker+i))); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent write failed, err: %v", err)
+	}
+
+	participants, err := s.GetSession(ctx, sessionKey)
+	if err != nil {
+		t.Fatalf("fail to get session, err: %v", err)
+	}
+	if len(participants) != workers {
+		t.Fatalf("expected %d participants, got %v", workers, participants)
+	}
+	messages, err := s.GetMessages(ctx, messageKey)
+	if err != nil {
+		t.Fatalf("fail to get messages, err: %v", err)
+	}
+	if len(messages) != workers*perWorker {
+		t.Fatalf("expected %d messages, got %d", workers*perWorker, len(messages))
+	}
+	seen := make(map[string]bool)
+	for _, m := range messages {
+		if seen[m.Hash] {
+			t.Fatalf("message %s stored twice", m.Hash)
+		}
+		seen[m.Hash] = true
+	}
+}
+
+// testInspector is skipped for storages that can't be inspected.
+func testInspector(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+	inspector, ok := storage.AsInspector(s)
+	if !ok {
+		t.Skip("storage is not an inspector")
+	}
+	// session IDs with separators and glob characters are listed as they are
+	sessionIDs := []string{"a:b*", "plain"}
+	for _, sessionID := range sessionIDs {
+		if err := s.SetSession(ctx, storage.SessionKey(sessionID), []string{"a"}); err != nil {
+			t.Fatalf("fail to set session, err: %v", err)
+		}
+		for _, key := range []string{storage.MessageKey(sessionID, "a", ""), storage.SetupKey(sessionID, "")} {
+			if err := s.AddSessionKey(ctx, sessionID, key); err != nil {
+				t.Fatalf("fail to add session key, err: %v", err)
+			}
+		}
+	}
+	// a session that only has tracked keys is listed too
+	if err := s.AddSessionKey(ctx, "index-only", storage.SetupKey("index-only", "")); err != nil {
+		t.Fatalf("fail to add session key, err: %v", err)
+	}
+
+	sessions, err := storage.ListSessions(ctx, inspector)
+	if err != nil {
+		t.Fatalf("fail to list sessions, err: %v", err)
+	}
+	if want := []string{"a:b*", "index-only", "plain"}; !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("expected sessions %v, got %v", want, sessions)
+	}
+	keys, err := inspector.SessionKeys(ctx, "a:b*")
+	if err != nil {
+		t.Fatalf("fail to get session keys, err: %v", err)
+	}
+	if want := []string{storage.MessageKey("a:b*", "a", ""), storage.SetupKey("a:b*", "")}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("expected session keys %v, got %v", want, keys)
+	}
+	keys, err = inspector.SessionKeys(ctx, "missing")
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("expected no keys for a missing session, got %v, err: %v", keys, err)
+	}
+
+	ttl, err := inspector.TTL(ctx, storage.SessionKey("plain"))
+	if err != nil {
+		t.Fatalf("fail to get ttl, err: %v", err)
+	}
+	if ttl <= 0 || ttl > (config.Expiration{}).SessionTTL() {
+		t.Fatalf("expected ttl within the session expiration, got %s", ttl)
+	}
+	if _, err := inspector.TTL(ctx, storage.SessionKey("missing")); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("missing key: expected ErrNotFound, got %v", err)
+	}
+
+	if err := s.DeleteSession(ctx, "plain"); err != nil {
+		t.Fatalf("fail to delete session, err: %v", err)
+	}
+	sessions, err = storage.ListSessions(ctx, inspector)
+	if err != nil {
+		t.Fatalf("fail to list sessions, err: %v", err)
+	}
+	if want := []string{"a:b*", "index-only"}; !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("expected sessions %v after delete, got %v", want, sessions)
+	}
+}
+
+// testUsers is skipped for storages that don't keep users.
+func testUsers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	s := h.open(t, config.Expiration{})
+	users, ok := storage.AsUserStore(s)
+	if !ok {
+		t.Skip("storage is not a user store")
+	}
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var saved []model.User
+	for i := 0; i < 3; i++ {
+		u := model.User{
+			APIKey:     fmt.Sprintf("key-%d", i),
+			CreatedAt:  sql.NullTime{Time: created, Valid: true},
+			NoOfVaults: int64(i),
+		}
+		if err := users.SaveUser(ctx, &u); err != nil {
+			t.Fatalf("fail to create user, err: %v", err)
+		}
+		if u.ID == 0 {
+			t.Fatal("expected an ID to be assigned")
+		}
+		saved = append(saved, u)
+	}
+	got, err := users.GetUser(ctx, saved[1].ID)
+	if err != nil {
+		t.Fatalf("fail to get user, err: %v", err)
+	}
+	if !got.CreatedAt.Time.Equal(created) || got.ExpiredAt.Valid || got.APIKey != "key-1" || got.NoOfVaults != 1 {
+		t.Fatalf("unexpected user %+v", got)
+	}
+
+	saved[1].IsPaid = true
+	saved[1].ExpiredAt = sql.NullTime{Time: created.Add(time.Hour), Valid: true}
+	if err := users.SaveUser(ctx, &saved[1]); err != nil {
+		t.Fatalf("fail to update user, err: %v", err)
+	}
+	if got, err = users.GetUser(ctx, saved[1].ID); err != nil || !got.IsPaid || !got.ExpiredAt.Valid {
+		t.Fatalf("expected the user to be updated, got %+v, err: %v", got, err)
+	}
+	missing := model.User{ID: saved[2].ID + 100, APIKey: "missing"}
+	if err := users.SaveUser(ctx, &missing); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("update of a missing user: expected ErrNotFound, got %v", err)
+	}
+
+	page, err := users.ListUsers(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("fail to list users, err: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != saved[0].ID || page[1].ID != saved[1].ID {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	page, err = users.ListUsers(ctx, page[1].ID, 2)
+	if err != nil {
+		t.Fatalf("fail to list users, err: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != saved[2].ID {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	if err := users.DeleteUser(ctx, saved[0].ID); err != nil {
+		t.Fatalf("fail to delete user, err: %v", err)
+	}
+	if err := users.DeleteUser(ctx, saved[0].ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("delete of a missing user: expected ErrNotFound, got %v", err)
+	}
+	if _, err := users.GetUser(ctx, saved[0].ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("deleted user: expected ErrNotFound, got %v", err)
+	}
+	// IDs are not reused
+	u := model.User{APIKey: "key-3"}
+	if err := users.SaveUser(ctx, &u); err != nil {
+		t.Fatalf("fail to create user, err: %v", err)
+	}
+	if u.ID <= saved[2].ID {
+		t.Fatalf("expected a new ID after %d, got %d", saved[2].ID, u.ID)
+	}
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"sort"
+
+	"github.com/vultisig/vultisig-relay/model"
+)
+
+// UserStore is implemented by the storages that keep user records, users never expire.
+type UserStore interface {
+	// ListUsers returns up to limit users with an ID greater than afterID, ordered by ID.
+	ListUsers(ctx context.Context, afterID int64, limit int) ([]model.User, error)
+	// GetUser returns the user with the given ID, or ErrNotFound.
+	GetUser(ctx context.Context, id int64) (model.User, error)
+	// SaveUser creates the user and assigns its ID when the ID is 0, otherwise it replaces the user,
+	// it returns ErrNotFound when the user to replace doesn't exist.
+	SaveUser(ctx context.Context, user *model.User) error
+	// DeleteUser removes the user with the given ID, or returns ErrNotFound.
+	DeleteUser(ctx context.Context, id int64) error
+}
+
+// AsUserStore returns the UserStore of the storage, looking through decorators.
+func AsUserStore(s Storage) (UserStore, bool) {
+	return unwrapAs[UserStore](s)
+}
+
+// pageUsers sorts the users by ID and returns the page after afterID.
+func pageUsers(users []model.User, afterID int64, limit int) []model.User {
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	page := []model.User{}
+	for _, u := range users {
+		if u.ID > afterID && len(page) < limit {
+			page = append(page, u)
+		}
+	}
+	return page
+}